@@ -6,9 +6,13 @@ import (
 )
 
 type Repository struct {
-	ID              uuid.UUID
-	Name            string `json:"name" gorm:"uniqueIndex:idx_name_owner"`
-	Owner           string `json:"owner" gorm:"uniqueIndex:idx_name_owner"`
+	ID uuid.UUID
+	// Provider is the kind of host the repository is read from and Host the
+	// host itself; owner and name are only unique within a host.
+	Provider        string `json:"provider"`
+	Host            string `json:"host" gorm:"not null;default:'';uniqueIndex:idx_repo_host_owner_name"`
+	Name            string `json:"name" gorm:"uniqueIndex:idx_repo_host_owner_name"`
+	Owner           string `json:"owner" gorm:"uniqueIndex:idx_repo_host_owner_name"`
 	Description     string `json:"description"`
 	URL             string `json:"html_url"`
 	Language        string `gorm:"index" json:"language"`
@@ -18,14 +22,55 @@ type Repository struct {
 	WatchersCount   int    `json:"watchers_count"`
 	CreatedAt       string `json:"created_at"`
 	UpdatedAt       string `json:"updated_at"`
+	// Tracked repositories are kept in sync by the update job. A repository is
+	// tracked while it is watched, an interest refers to it or the reconciler
+	// still finds it under a tracked owner.
+	Tracked bool `json:"tracked" gorm:"not null;default:false"`
+	// Watched repositories were put on the watchlist explicitly and stay tracked
+	// regardless of interests.
+	Watched bool `json:"watched" gorm:"not null;default:false"`
+	// PollIntervalMinutes is how often the repository is synced, the default
+	// interval when zero.
+	PollIntervalMinutes int        `json:"poll_interval_minutes"`
+	SyncEnabled         bool       `json:"sync_enabled" gorm:"not null;default:true"`
+	LastSyncedAt        *time.Time `json:"last_synced_at"`
+	// FreshnessTTLMinutes is how long, in minutes, the record is served without
+	// asking the provider again, the default TTL when zero.
+	FreshnessTTLMinutes int `json:"freshness_ttl_minutes"`
+	// FetchedAt is when the provider last returned or confirmed the record.
+	FetchedAt *time.Time `json:"fetched_at"`
+	// Cached marks a record served from storage without asking the provider,
+	// because it was still fresh.
+	Cached bool `json:"cached" gorm:"-"`
+	// Stale marks a stored record served because the provider was unavailable.
+	Stale bool `json:"stale,omitempty" gorm:"-"`
 }
 
 type Commit struct {
 	ID          uuid.UUID
-	RepoID      uuid.UUID `json:"repo_id"`
-	SHA         string    `json:"sha"`
+	RepoID      uuid.UUID `json:"repo_id" gorm:"uniqueIndex:idx_commit_repo_sha"`
+	SHA         string    `json:"sha" gorm:"uniqueIndex:idx_commit_repo_sha"`
 	AuthorName  string    `json:"author_name"`
 	AuthorEmail string    `json:"author_email"`
 	Message     string    `json:"message"`
 	CommitDate  time.Time `json:"commit_date"`
 }
+
+// CommitCursor records how far the commit history of a repository has been synced
+// so the next sync only asks the provider for newer commits.
+type CommitCursor struct {
+	RepoID uuid.UUID `json:"repo_id" gorm:"primaryKey"`
+	// LastSHA and LastCommitDate point at the newest commit up to which the
+	// history is stored without gaps. The date is the committer date, which
+	// providers filter the history by.
+	LastSHA        string    `json:"last_sha"`
+	LastCommitDate time.Time `json:"last_commit_date"`
+	// BackfillUntil is the committer date of the oldest commit fetched by a sync
+	// that stopped at the page cap, nil unless such a gap is left. Later syncs
+	// fetch the history between LastCommitDate and BackfillUntil first.
+	BackfillUntil *time.Time `json:"backfill_until"`
+	// PendingSHA and PendingCommitDate point at the newest commit fetched above
+	// the gap, where the cursor moves once the gap is backfilled.
+	PendingSHA        string    `json:"pending_sha"`
+	PendingCommitDate time.Time `json:"pending_commit_date"`
+}
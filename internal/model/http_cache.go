@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// HTTPCacheEntry keeps the ETag/Last-Modified validators of an upstream URL.
+type HTTPCacheEntry struct {
+	URL          string `gorm:"primaryKey"`
+	ETag         string
+	LastModified string
+	UpdatedAt    time.Time
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project/pkg/object"
+)
+
+// Interest is a saved repository search on Host, run every IntervalMinutes
+// while enabled.
+type Interest struct {
+	ID              uuid.UUID          `json:"id"`
+	Name            string             `json:"name" gorm:"uniqueIndex"`
+	Host            string             `json:"host" gorm:"not null;default:''"`
+	Query           object.SearchQuery `json:"query" gorm:"serializer:json"`
+	IntervalMinutes int                `json:"interval_minutes"`
+	Enabled         bool               `json:"enabled"`
+	LastRunAt       *time.Time         `json:"last_run_at"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
+
+// Due reports whether the interest should be searched again at now.
+func (i Interest) Due(now time.Time) bool {
+	if !i.Enabled {
+		return false
+	}
+	if i.LastRunAt == nil {
+		return true
+	}
+	return !now.Before(i.LastRunAt.Add(time.Duration(i.IntervalMinutes) * time.Minute))
+}
+
+// RepositoryInterest records that a search for an interest discovered a repository.
+type RepositoryInterest struct {
+	RepositoryID uuid.UUID `json:"repository_id" gorm:"primaryKey"`
+	InterestID   uuid.UUID `json:"interest_id" gorm:"primaryKey;index"`
+	DiscoveredAt time.Time `json:"discovered_at"`
+}
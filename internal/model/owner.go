@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	OwnerKindOrg  = "org"
+	OwnerKindUser = "user"
+)
+
+// TrackedOwner is an organization or user whose repositories are all tracked.
+// The reconciler lists them every IntervalMinutes while enabled.
+type TrackedOwner struct {
+	ID              uuid.UUID  `json:"id"`
+	Host            string     `json:"host" gorm:"not null;default:'';uniqueIndex:idx_owner_host_login"`
+	Login           string     `json:"login" gorm:"uniqueIndex:idx_owner_host_login"`
+	Kind            string     `json:"kind"`
+	IncludeForks    bool       `json:"include_forks"`
+	IncludeArchived bool       `json:"include_archived"`
+	IntervalMinutes int        `json:"interval_minutes"`
+	Enabled         bool       `json:"enabled"`
+	LastRunAt       *time.Time `json:"last_run_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Due reports whether the owner should be reconciled again at now.
+func (o TrackedOwner) Due(now time.Time) bool {
+	if !o.Enabled {
+		return false
+	}
+	if o.LastRunAt == nil {
+		return true
+	}
+	return !now.Before(o.LastRunAt.Add(time.Duration(o.IntervalMinutes) * time.Minute))
+}
+
+// OwnerRepository links a repository to the tracked owner listing it. SeenAt is
+// the start of the last reconciliation that found it; MissingSince is set once
+// a reconciliation no longer does, e.g. after the repository was deleted or
+// transferred.
+type OwnerRepository struct {
+	RepositoryID   uuid.UUID  `json:"repository_id" gorm:"primaryKey"`
+	TrackedOwnerID uuid.UUID  `json:"tracked_owner_id" gorm:"primaryKey;index"`
+	SeenAt         time.Time  `json:"seen_at"`
+	MissingSince   *time.Time `json:"missing_since"`
+}
+
+// OwnedRepository is a stored repository as listed for its tracked owner.
+type OwnedRepository struct {
+	Repository
+	MissingSince *time.Time `json:"missing_since"`
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// uniqueViolation is the SQLSTATE Postgres reports for a unique index conflict.
+const uniqueViolation = "23505"
+
+// ErrDuplicate is returned when a record clashes with one already stored under
+// a unique index.
+var ErrDuplicate = errors.New("duplicate record")
+
+// translate reports unique index conflicts as ErrDuplicate.
+func translate(err error) error {
+	var state interface{ SQLState() string }
+	if errors.Is(err, gorm.ErrDuplicatedKey) || (errors.As(err, &state) && state.SQLState() == uniqueViolation) {
+		return ErrDuplicate
+	}
+	return err
+}
@@ -3,16 +3,26 @@ package repository
 import (
 	"context"
 	"errors"
+	"github.com/google/uuid"
 	"github.com/project/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type IGitRepo interface {
 	CreateRepoRecord(context.Context, model.Repository) error
 	UpdateRepoRecord(context.Context, model.Repository) error
-	CreateCommitRecord(context.Context, []model.Commit) error
-	GetRepo(context.Context, string, string) (*model.Repository, error)
-	GetRepos(context.Context, int, int) ([]model.Repository, int64, error)
+	UpsertCommitRecords(context.Context, []model.Commit) error
+	GetCommits(context.Context, uuid.UUID, int) ([]model.Commit, error)
+	GetCommitCursor(context.Context, uuid.UUID) (*model.CommitCursor, error)
+	SaveCommitCursor(context.Context, model.CommitCursor) error
+	GetRepo(context.Context, string, string, string) (*model.Repository, error)
+	GetDueRepos(context.Context, time.Time, int, uuid.UUID, int) ([]model.Repository, error)
+	MarkRepoSynced(context.Context, uuid.UUID, time.Time) error
+	MarkRepoFetched(context.Context, uuid.UUID, time.Time) error
+	WatchRepo(context.Context, uuid.UUID, int, int, bool) error
+	UnwatchRepo(context.Context, uuid.UUID) error
 	GetReposByLanguage(context.Context, string) ([]model.Repository, error)
 	GetTopNRepoByStarCount(context.Context, int) ([]model.Repository, error)
 }
@@ -35,13 +45,44 @@ func (g gitRepo) UpdateRepoRecord(ctx context.Context, repository model.Reposito
 	return g.db.WithContext(ctx).Where("id = ?", repository.ID).Updates(&repository).Error
 }
 
-func (g gitRepo) CreateCommitRecord(ctx context.Context, commit []model.Commit) error {
-	return g.db.WithContext(ctx).Create(&commit).Error
+// UpsertCommitRecords inserts commits, refreshing the stored copy of any commit
+// already recorded for the same repository and SHA instead of duplicating it.
+func (g gitRepo) UpsertCommitRecords(ctx context.Context, commits []model.Commit) error {
+	return g.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "repo_id"}, {Name: "sha"}},
+		DoUpdates: clause.AssignmentColumns([]string{"author_name", "author_email", "message", "commit_date"}),
+	}).Create(&commits).Error
 }
 
-func (g gitRepo) GetRepo(ctx context.Context, owner, name string) (*model.Repository, error) {
+func (g gitRepo) GetCommits(ctx context.Context, repoID uuid.UUID, limit int) ([]model.Commit, error) {
+	var commits []model.Commit
+
+	if err := g.db.WithContext(ctx).Where("repo_id = ?", repoID).Order("commit_date desc").Limit(limit).Find(&commits).Error; err != nil {
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+func (g gitRepo) GetCommitCursor(ctx context.Context, repoID uuid.UUID) (*model.CommitCursor, error) {
+	var cursor model.CommitCursor
+	if err := g.db.WithContext(ctx).Where("repo_id = ?", repoID).First(&cursor).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+func (g gitRepo) SaveCommitCursor(ctx context.Context, cursor model.CommitCursor) error {
+	return g.db.WithContext(ctx).Save(&cursor).Error
+}
+
+func (g gitRepo) GetRepo(ctx context.Context, host, owner, name string) (*model.Repository, error) {
 	var resp model.Repository
-	if err := g.db.WithContext(ctx).Where("owner = ? AND name = ?", owner, name).First(&resp).Error; err != nil {
+	if err := g.db.WithContext(ctx).Where("host = ? AND owner = ? AND name = ?", host, owner, name).First(&resp).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -51,23 +92,70 @@ func (g gitRepo) GetRepo(ctx context.Context, owner, name string) (*model.Reposi
 	return &resp, nil
 }
 
-func (g gitRepo) GetRepos(ctx context.Context, size, page int) ([]model.Repository, int64, error) {
-	offset := size * (page - 1)
-	var (
-		resp  []model.Repository
-		total int64
-	)
+// GetDueRepos returns up to limit tracked repositories, ordered by id and after
+// the given id, whose sync is enabled and whose poll interval has passed at now.
+// Repositories without an interval of their own use defaultInterval minutes.
+func (g gitRepo) GetDueRepos(ctx context.Context, now time.Time, defaultInterval int, after uuid.UUID, limit int) ([]model.Repository, error) {
+	var repos []model.Repository
 
-	if err := g.db.WithContext(ctx).Model(&model.Repository{}).Count(&total).Error; err != nil {
-		return nil, 0, err
+	if err := g.db.WithContext(ctx).
+		Where("tracked = ? AND sync_enabled = ? AND id > ?", true, true, after).
+		Where("last_synced_at IS NULL OR last_synced_at + make_interval(mins => COALESCE(NULLIF(poll_interval_minutes, 0), ?)) <= ?", defaultInterval, now).
+		Order("id").
+		Limit(limit).
+		Find(&repos).Error; err != nil {
+		return nil, err
 	}
 
-	total = total / int64(size)
-	if err := g.db.WithContext(ctx).Model(&model.Repository{}).Offset(offset).Limit(size).Find(&resp).Error; err != nil {
-		return nil, 0, err
+	return repos, nil
+}
+
+func (g gitRepo) MarkRepoSynced(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return g.db.WithContext(ctx).Model(&model.Repository{}).Where("id = ?", id).Update("last_synced_at", at).Error
+}
+
+// MarkRepoFetched records that the provider confirmed the stored record at at.
+func (g gitRepo) MarkRepoFetched(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return g.db.WithContext(ctx).Model(&model.Repository{}).Where("id = ?", id).Update("fetched_at", at).Error
+}
+
+// WatchRepo puts the repository on the watchlist with the given schedule and
+// freshness TTL.
+func (g gitRepo) WatchRepo(ctx context.Context, id uuid.UUID, pollIntervalMinutes, freshnessTTLMinutes int, enabled bool) error {
+	return g.db.WithContext(ctx).Model(&model.Repository{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"watched":               true,
+		"tracked":               true,
+		"poll_interval_minutes": pollIntervalMinutes,
+		"freshness_ttl_minutes": freshnessTTLMinutes,
+		"sync_enabled":          enabled,
+	}).Error
+}
+
+// referencedSQL matches the repositories an interest or a tracked owner still
+// refers to, which keeps them tracked without being watched.
+const referencedSQL = `(EXISTS (SELECT 1 FROM repository_interests ri WHERE ri.repository_id = repositories.id)
+	OR EXISTS (SELECT 1 FROM owner_repositories o WHERE o.repository_id = repositories.id AND o.missing_since IS NULL))`
+
+// UnwatchRepo takes the repository off the watchlist. It stays tracked while an
+// interest or a tracked owner still refers to it.
+func (g gitRepo) UnwatchRepo(ctx context.Context, id uuid.UUID) error {
+	return g.db.WithContext(ctx).Model(&model.Repository{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"watched": false,
+		"tracked": gorm.Expr(referencedSQL),
+	}).Error
+}
+
+// untrackUnreferenced stops tracking the given repositories unless they are
+// watched or still referred to.
+func untrackUnreferenced(tx *gorm.DB, repoIDs []uuid.UUID) error {
+	if len(repoIDs) == 0 {
+		return nil
 	}
 
-	return resp, total, nil
+	return tx.Model(&model.Repository{}).
+		Where("id IN ? AND watched = ?", repoIDs, false).
+		Where("NOT "+referencedSQL).
+		Update("tracked", false).Error
 }
 
 func (g gitRepo) GetReposByLanguage(ctx context.Context, language string) ([]model.Repository, error) {
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/project/internal/model"
+	"github.com/project/pkg/github"
+	"gorm.io/gorm"
+)
+
+type httpCache struct {
+	db *gorm.DB
+}
+
+// NewHTTPCache returns a github.Cache that persists response validators in Postgres,
+// so conditional requests keep working across restarts.
+func NewHTTPCache(db *gorm.DB) github.Cache {
+	return httpCache{
+		db: db,
+	}
+}
+
+func (h httpCache) Get(ctx context.Context, url string) (*github.CacheEntry, error) {
+	var entry model.HTTPCacheEntry
+	if err := h.db.WithContext(ctx).Where("url = ?", url).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &github.CacheEntry{
+		ETag:         entry.ETag,
+		LastModified: entry.LastModified,
+	}, nil
+}
+
+func (h httpCache) Set(ctx context.Context, url string, entry github.CacheEntry) error {
+	return h.db.WithContext(ctx).Save(&model.HTTPCacheEntry{
+		URL:          url,
+		ETag:         entry.ETag,
+		LastModified: entry.LastModified,
+	}).Error
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IInterestRepo interface {
+	CreateInterest(context.Context, model.Interest) error
+	UpdateInterest(context.Context, model.Interest) error
+	DeleteInterest(context.Context, uuid.UUID) error
+	GetInterest(context.Context, uuid.UUID) (*model.Interest, error)
+	GetInterests(context.Context) ([]model.Interest, error)
+	MarkInterestRun(context.Context, uuid.UUID, time.Time) error
+	LinkRepo(context.Context, uuid.UUID, uuid.UUID) error
+	GetInterestRepos(context.Context, uuid.UUID) ([]model.Repository, error)
+}
+
+type interestRepo struct {
+	db *gorm.DB
+}
+
+func NewInterestDBRepo(db *gorm.DB) IInterestRepo {
+	return interestRepo{
+		db: db,
+	}
+}
+
+// CreateInterest stores a new interest, returning ErrDuplicate when its name is taken.
+func (r interestRepo) CreateInterest(ctx context.Context, interest model.Interest) error {
+	return translate(r.db.WithContext(ctx).Create(&interest).Error)
+}
+
+// UpdateInterest overwrites every editable field of the interest, so it can be
+// disabled or have its schedule cleared. Renaming it to a name taken by another
+// interest returns ErrDuplicate.
+func (r interestRepo) UpdateInterest(ctx context.Context, interest model.Interest) error {
+	return translate(r.db.WithContext(ctx).Model(&model.Interest{ID: interest.ID}).
+		Select("name", "host", "query", "interval_minutes", "enabled").
+		Updates(&interest).Error)
+}
+
+// DeleteInterest removes the interest and its repository links, and stops
+// tracking the repositories nothing else keeps tracked.
+func (r interestRepo) DeleteInterest(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var repoIDs []uuid.UUID
+		if err := tx.Model(&model.RepositoryInterest{}).Where("interest_id = ?", id).Pluck("repository_id", &repoIDs).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("interest_id = ?", id).Delete(&model.RepositoryInterest{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", id).Delete(&model.Interest{}).Error; err != nil {
+			return err
+		}
+
+		return untrackUnreferenced(tx, repoIDs)
+	})
+}
+
+func (r interestRepo) GetInterest(ctx context.Context, id uuid.UUID) (*model.Interest, error) {
+	var interest model.Interest
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&interest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &interest, nil
+}
+
+func (r interestRepo) GetInterests(ctx context.Context) ([]model.Interest, error) {
+	var interests []model.Interest
+
+	if err := r.db.WithContext(ctx).Order("name").Find(&interests).Error; err != nil {
+		return nil, err
+	}
+
+	return interests, nil
+}
+
+func (r interestRepo) MarkInterestRun(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.Interest{}).Where("id = ?", id).Update("last_run_at", at).Error
+}
+
+// LinkRepo records that the interest discovered the repository, tracking the
+// repository again if it had been dropped before.
+func (r interestRepo) LinkRepo(ctx context.Context, interestID, repoID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		link := model.RepositoryInterest{RepositoryID: repoID, InterestID: interestID, DiscoveredAt: time.Now()}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&link).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&model.Repository{}).Where("id = ? AND tracked = ?", repoID, false).Update("tracked", true).Error
+	})
+}
+
+func (r interestRepo) GetInterestRepos(ctx context.Context, interestID uuid.UUID) ([]model.Repository, error) {
+	var repos []model.Repository
+
+	if err := r.db.WithContext(ctx).
+		Joins("JOIN repository_interests ri ON ri.repository_id = repositories.id").
+		Where("ri.interest_id = ?", interestID).
+		Order("repositories.stars_count desc").
+		Find(&repos).Error; err != nil {
+		return nil, err
+	}
+
+	return repos, nil
+}
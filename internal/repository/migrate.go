@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/project/internal/model"
+	"github.com/project/pkg/object"
+	"gorm.io/gorm"
+	"log"
+)
+
+// Migrate brings the schema up to date, running the one-off data fixes that
+// have to happen before AutoMigrate can add new constraints. Rows stored before
+// repositories were keyed by host are assigned to defaultHost.
+func Migrate(db *gorm.DB, defaultHost string) error {
+	if err := dedupeCommits(db); err != nil {
+		return err
+	}
+
+	seed := !db.Migrator().HasTable(&model.Interest{})
+	if err := db.AutoMigrate(&model.Repository{}, &model.Commit{}, &model.CommitCursor{}, &model.HTTPCacheEntry{},
+		&model.Interest{}, &model.RepositoryInterest{}, &model.TrackedOwner{}, &model.OwnerRepository{}); err != nil {
+		return err
+	}
+
+	if err := backfillHosts(db, defaultHost); err != nil {
+		return err
+	}
+	if seed {
+		return seedInterests(db, defaultHost)
+	}
+	return nil
+}
+
+// seedInterests stores the search for cryptocurrency repositories that used to
+// be hardcoded, so deployments keep discovering repositories once interests
+// move to the database. It only runs when the interests table is created;
+// interests deleted later stay deleted.
+func seedInterests(db *gorm.DB, defaultHost string) error {
+	interest := model.Interest{
+		ID:              uuid.New(),
+		Name:            "cryptocurrency",
+		Host:            defaultHost,
+		Query:           object.SearchQuery{Keywords: "cryptocurrency"},
+		IntervalMinutes: 60,
+		Enabled:         true,
+	}
+	if err := db.Create(&interest).Error; err != nil {
+		return err
+	}
+
+	log.Printf("seeded the %q interest", interest.Name)
+	return nil
+}
+
+// backfillHosts assigns the rows without a host to defaultHost and drops the
+// indexes that kept owner and name unique across hosts. It is a no-op once
+// every row has a host.
+func backfillHosts(db *gorm.DB, defaultHost string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Repository{}).Where("host = ?", "").
+			Updates(map[string]interface{}{"host": defaultHost, "provider": "github"}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.Interest{}).Where("host = ?", "").Update("host", defaultHost).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.TrackedOwner{}).Where("host = ?", "").Update("host", defaultHost).Error; err != nil {
+			return err
+		}
+
+		migrator := tx.Migrator()
+		if migrator.HasIndex(&model.Repository{}, "idx_name_owner") {
+			if err := migrator.DropIndex(&model.Repository{}, "idx_name_owner"); err != nil {
+				return err
+			}
+		}
+		if migrator.HasIndex(&model.TrackedOwner{}, "idx_tracked_owners_login") {
+			return migrator.DropIndex(&model.TrackedOwner{}, "idx_tracked_owners_login")
+		}
+
+		return nil
+	})
+}
+
+// dedupeCommits collapses commits recorded more than once for the same repository,
+// keeping the first row, so the unique (repo_id, sha) index can be created.
+// It is a no-op once that index exists.
+func dedupeCommits(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&model.Commit{}) || migrator.HasIndex(&model.Commit{}, "idx_commit_repo_sha") {
+		return nil
+	}
+
+	result := db.Exec(`DELETE FROM commits a USING commits b
+		WHERE a.repo_id = b.repo_id AND a.sha = b.sha AND a.ctid > b.ctid`)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("removed %d duplicate commit records", result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project/internal/model"
+	"github.com/project/pkg/object"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IOwnerRepo interface {
+	CreateTrackedOwner(context.Context, model.TrackedOwner) error
+	UpdateTrackedOwner(context.Context, model.TrackedOwner) error
+	DeleteTrackedOwner(context.Context, uuid.UUID) error
+	GetTrackedOwner(context.Context, uuid.UUID) (*model.TrackedOwner, error)
+	GetTrackedOwners(context.Context) ([]model.TrackedOwner, error)
+	MarkOwnerRun(context.Context, uuid.UUID, time.Time) error
+	LinkOwnerRepo(context.Context, uuid.UUID, uuid.UUID, time.Time) error
+	FlagMissingOwnerRepos(context.Context, uuid.UUID, time.Time) (int64, error)
+	UnlinkOwnerRepos(context.Context, uuid.UUID, string, []object.RepoRef) (int64, error)
+	GetOwnerRepos(context.Context, uuid.UUID) ([]model.OwnedRepository, error)
+}
+
+type ownerRepo struct {
+	db *gorm.DB
+}
+
+func NewOwnerDBRepo(db *gorm.DB) IOwnerRepo {
+	return ownerRepo{
+		db: db,
+	}
+}
+
+// CreateTrackedOwner stores a new owner, returning ErrDuplicate when the login
+// is already tracked on its host.
+func (r ownerRepo) CreateTrackedOwner(ctx context.Context, owner model.TrackedOwner) error {
+	return translate(r.db.WithContext(ctx).Create(&owner).Error)
+}
+
+// UpdateTrackedOwner overwrites the filters and schedule of the owner.
+func (r ownerRepo) UpdateTrackedOwner(ctx context.Context, owner model.TrackedOwner) error {
+	return r.db.WithContext(ctx).Model(&model.TrackedOwner{ID: owner.ID}).
+		Select("include_forks", "include_archived", "interval_minutes", "enabled").
+		Updates(&owner).Error
+}
+
+// DeleteTrackedOwner removes the owner and its repository links, and stops
+// tracking the repositories nothing else keeps tracked.
+func (r ownerRepo) DeleteTrackedOwner(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var repoIDs []uuid.UUID
+		if err := tx.Model(&model.OwnerRepository{}).Where("tracked_owner_id = ?", id).Pluck("repository_id", &repoIDs).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("tracked_owner_id = ?", id).Delete(&model.OwnerRepository{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id = ?", id).Delete(&model.TrackedOwner{}).Error; err != nil {
+			return err
+		}
+
+		return untrackUnreferenced(tx, repoIDs)
+	})
+}
+
+func (r ownerRepo) GetTrackedOwner(ctx context.Context, id uuid.UUID) (*model.TrackedOwner, error) {
+	var owner model.TrackedOwner
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&owner).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &owner, nil
+}
+
+func (r ownerRepo) GetTrackedOwners(ctx context.Context) ([]model.TrackedOwner, error) {
+	var owners []model.TrackedOwner
+
+	if err := r.db.WithContext(ctx).Order("login").Find(&owners).Error; err != nil {
+		return nil, err
+	}
+
+	return owners, nil
+}
+
+func (r ownerRepo) MarkOwnerRun(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.TrackedOwner{}).Where("id = ?", id).Update("last_run_at", at).Error
+}
+
+// LinkOwnerRepo records that the reconciliation started at seenAt found the
+// repository under the owner, clearing an earlier missing flag and tracking it.
+func (r ownerRepo) LinkOwnerRepo(ctx context.Context, ownerID, repoID uuid.UUID, seenAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		link := model.OwnerRepository{RepositoryID: repoID, TrackedOwnerID: ownerID, SeenAt: seenAt}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "repository_id"}, {Name: "tracked_owner_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"seen_at": seenAt, "missing_since": nil}),
+		}).Create(&link).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&model.Repository{}).Where("id = ? AND tracked = ?", repoID, false).Update("tracked", true).Error
+	})
+}
+
+// UnlinkOwnerRepos removes the links of the owner to the given repositories of
+// host, which its filters leave out, and stops tracking those nothing else
+// keeps tracked. It returns the number of links removed.
+func (r ownerRepo) UnlinkOwnerRepos(ctx context.Context, ownerID uuid.UUID, host string, repos []object.RepoRef) (int64, error) {
+	if len(repos) == 0 {
+		return 0, nil
+	}
+
+	names := make([][]interface{}, 0, len(repos))
+	for _, repo := range repos {
+		names = append(names, []interface{}{repo.Owner, repo.Name})
+	}
+
+	var unlinked int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var repoIDs []uuid.UUID
+		if err := tx.Model(&model.Repository{}).
+			Where("host = ? AND (owner, name) IN ?", host, names).
+			Pluck("id", &repoIDs).Error; err != nil {
+			return err
+		}
+		if len(repoIDs) == 0 {
+			return nil
+		}
+
+		result := tx.Where("tracked_owner_id = ? AND repository_id IN ?", ownerID, repoIDs).Delete(&model.OwnerRepository{})
+		if result.Error != nil {
+			return result.Error
+		}
+		unlinked = result.RowsAffected
+
+		return untrackUnreferenced(tx, repoIDs)
+	})
+
+	return unlinked, err
+}
+
+// FlagMissingOwnerRepos flags the repositories of the owner the reconciliation
+// started at since did not find, and stops tracking those nothing else keeps
+// tracked. It returns the number of repositories newly flagged.
+func (r ownerRepo) FlagMissingOwnerRepos(ctx context.Context, ownerID uuid.UUID, since time.Time) (int64, error) {
+	var flagged int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var repoIDs []uuid.UUID
+		if err := tx.Model(&model.OwnerRepository{}).
+			Where("tracked_owner_id = ? AND seen_at < ? AND missing_since IS NULL", ownerID, since).
+			Pluck("repository_id", &repoIDs).Error; err != nil {
+			return err
+		}
+		if len(repoIDs) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&model.OwnerRepository{}).
+			Where("tracked_owner_id = ? AND repository_id IN ?", ownerID, repoIDs).
+			Update("missing_since", since).Error; err != nil {
+			return err
+		}
+		flagged = int64(len(repoIDs))
+
+		return untrackUnreferenced(tx, repoIDs)
+	})
+
+	return flagged, err
+}
+
+func (r ownerRepo) GetOwnerRepos(ctx context.Context, ownerID uuid.UUID) ([]model.OwnedRepository, error) {
+	var repos []model.OwnedRepository
+
+	if err := r.db.WithContext(ctx).Model(&model.Repository{}).
+		Select("repositories.*, o.missing_since").
+		Joins("JOIN owner_repositories o ON o.repository_id = repositories.id").
+		Where("o.tracked_owner_id = ?", ownerID).
+		Order("repositories.name").
+		Scan(&repos).Error; err != nil {
+		return nil, err
+	}
+
+	return repos, nil
+}
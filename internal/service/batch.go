@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/project/internal/model"
+	"github.com/project/pkg/object"
+)
+
+// syncBatch refreshes repos and the commits made since their cursors. Histories
+// that do not fit in the batched page are completed with FetchCommits. It only
+// fails when the batch has to wait, with a BudgetExhaustedError for the next
+// quota window or an object.UnavailableError; other errors are logged.
+func (g gitInfo) syncBatch(ctx context.Context, p Provider, batcher object.BatchFetcher, repos []model.Repository) error {
+	ctx = g.budget.admitting(ctx, p)
+
+	var (
+		refs    = make([]object.RepoRef, 0, len(repos))
+		writers = make(map[string]*commitWriter, len(repos))
+		records = make(map[string]model.Repository, len(repos))
+	)
+	for _, repo := range repos {
+		writer, err := g.newCommitWriter(ctx, repo.ID)
+		if err != nil {
+			log.Printf("error fetching commit cursor for %s/%s, err %v", repo.Owner, repo.Name, err)
+			continue
+		}
+
+		key := repoKey(repo.Owner, repo.Name)
+		writers[key] = writer
+		records[key] = repo
+		refs = append(refs, object.RepoRef{Owner: repo.Owner, Name: repo.Name, Commits: writer.options()})
+	}
+
+	var (
+		snapshots []object.RepoSnapshot
+		err       error
+	)
+	for {
+		snapshots, err = batcher.FetchRepos(ctx, refs)
+		if err != nil {
+			retry, waitErr := waitForReset(ctx, err)
+			if waitErr != nil {
+				log.Printf("error fetching repository batch, err %v", waitErr)
+				return nil
+			}
+			if retry {
+				continue
+			}
+			if isDeferred(err) || isUnavailable(err) {
+				return err
+			}
+			log.Printf("error fetching repository batch, err %v", err)
+			return nil
+		}
+
+		break
+	}
+
+	for _, snapshot := range snapshots {
+		key := repoKey(snapshot.Owner, snapshot.Name)
+		record, ok := records[key]
+		if !ok {
+			log.Printf("batch returned unexpected repository %s/%s", snapshot.Owner, snapshot.Name)
+			continue
+		}
+		delete(records, key)
+
+		if err := g.repo.UpdateRepoRecord(ctx, repositoryRecord(record.ID, p, record.Owner, snapshot.Repository)); err != nil {
+			log.Printf("error updating record with id: %s, error: %v", record.ID, err)
+			continue
+		}
+
+		writer := writers[key]
+		if err := writer.pageFunc(ctx)(snapshot.Commits); err != nil {
+			log.Printf("error persisting commits for %s/%s, err %v", record.Owner, record.Name, err)
+			continue
+		}
+
+		if snapshot.HasMoreCommits {
+			err = g.syncHistory(ctx, p, record.Owner, record.Name, writer)
+		} else {
+			var closed bool
+			if closed, err = writer.saveCursor(ctx, false); err == nil && closed {
+				// the batch closed a gap, the commits above it are still due
+				err = g.syncHistory(ctx, p, record.Owner, record.Name, writer)
+			}
+		}
+		if err != nil {
+			log.Printf("error syncing commits for %s/%s, err %v", record.Owner, record.Name, err)
+			continue
+		}
+		g.markSynced(ctx, record)
+	}
+
+	for _, missing := range records {
+		log.Printf("repository %s/%s was not returned by the provider", missing.Owner, missing.Name)
+	}
+
+	return nil
+}
+
+func repoKey(owner, name string) string {
+	return strings.ToLower(fmt.Sprintf("%s/%s", owner, name))
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+// Job is a kind of work competing for the quota of a provider.
+type Job string
+
+const (
+	// JobRefresh refreshes the metadata of tracked repositories.
+	JobRefresh Job = "refresh"
+	// JobCommits syncs the commit histories of tracked repositories.
+	JobCommits Job = "commits"
+	// JobSearch discovers repositories through interests and tracked owners.
+	JobSearch Job = "search"
+	// JobInteractive serves API requests; contexts without a job run as it.
+	JobInteractive Job = "interactive"
+)
+
+// BudgetShares divides the hourly quota of a provider between jobs, as
+// fractions adding up to at most 1. The share of JobInteractive is a reserve
+// the background jobs never touch.
+type BudgetShares map[Job]float64
+
+// DefaultBudgetShares is used for providers registered without shares of their own.
+var DefaultBudgetShares = BudgetShares{
+	JobRefresh:     0.1,
+	JobCommits:     0.6,
+	JobSearch:      0.2,
+	JobInteractive: 0.1,
+}
+
+// ParseBudgetShares reads shares given in percent, such as
+// "refresh=10,commits=60,search=20,interactive=10". Jobs left out get no share.
+func ParseBudgetShares(value string) (BudgetShares, error) {
+	shares := make(BudgetShares)
+	total := 0.0
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, percent, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid budget share %q, want job=percent", part)
+		}
+		job := Job(strings.TrimSpace(name))
+		switch job {
+		case JobRefresh, JobCommits, JobSearch, JobInteractive:
+		default:
+			return nil, fmt.Errorf("unknown job %q in budget shares", job)
+		}
+		p, err := strconv.ParseFloat(strings.TrimSpace(percent), 64)
+		if err != nil || p < 0 {
+			return nil, fmt.Errorf("invalid budget share %q for job %s", percent, job)
+		}
+
+		shares[job] = p / 100
+		total += p
+	}
+	if total > 100 {
+		return nil, fmt.Errorf("budget shares add up to %g%%, more than the whole quota", total)
+	}
+
+	return shares, nil
+}
+
+type jobKey struct{}
+
+// WithJob spends the quota used on behalf of ctx from the share of job.
+func WithJob(ctx context.Context, job Job) context.Context {
+	return context.WithValue(ctx, jobKey{}, job)
+}
+
+func jobOf(ctx context.Context) Job {
+	job, ok := ctx.Value(jobKey{}).(Job)
+	if !ok {
+		return JobInteractive
+	}
+	return job
+}
+
+// BudgetExhaustedError reports that a job spent its share of the quota of a
+// host. The job is deferred until the quota resets rather than starving the others.
+type BudgetExhaustedError struct {
+	Host     string
+	Job      Job
+	Resource string
+	Reset    time.Time
+}
+
+func (e *BudgetExhaustedError) Error() string {
+	return fmt.Sprintf("%s share of the %s quota of %s spent until %s", e.Job, e.Resource, e.Host, e.Reset.Format(time.RFC3339))
+}
+
+// isDeferred reports whether err is a job running out of its budget share.
+func isDeferred(err error) bool {
+	var exhausted *BudgetExhaustedError
+	return errors.As(err, &exhausted)
+}
+
+// budgetedResources are the hourly quotas split between jobs. The search quota
+// resets every minute and is only paced by the providers.
+var budgetedResources = map[string]bool{
+	object.ResourceCore:    true,
+	object.ResourceGraphQL: true,
+}
+
+type budgetKey struct {
+	host     string
+	resource string
+}
+
+// budgetWindow counts what every job spent until the quota resets: requests
+// for core, points for graphql.
+type budgetWindow struct {
+	reset time.Time
+	spent map[Job]int
+}
+
+// budgetManager hands out the quota of every provider reporting a Budget to
+// the jobs sharing it.
+type budgetManager struct {
+	mu      sync.Mutex
+	windows map[budgetKey]*budgetWindow
+	now     func() time.Time
+}
+
+func newBudgetManager() *budgetManager {
+	return &budgetManager{windows: make(map[budgetKey]*budgetWindow), now: time.Now}
+}
+
+// admitting makes the requests sent to p on behalf of ctx spend the share of
+// their job. Providers without a Budget are not arbitrated.
+func (m *budgetManager) admitting(ctx context.Context, p Provider) context.Context {
+	if m == nil {
+		return ctx
+	}
+	reporter, ok := p.Details.(object.BudgetReporter)
+	if !ok {
+		return ctx
+	}
+	shares := p.BudgetShares
+	if shares == nil {
+		shares = DefaultBudgetShares
+	}
+
+	return object.WithAdmitter(ctx, func(ctx context.Context, resource string) error {
+		if !budgetedResources[resource] {
+			return nil
+		}
+		budget, ok := reporter.Budget(resource)
+		if !ok {
+			return nil
+		}
+		return m.spend(p.Host, shares, jobOf(ctx), budget)
+	})
+}
+
+// spend takes the cost of a request, one unit or the points a graphql query is
+// expected to take, of budget from the share of job. Interactive requests past
+// their reserve borrow what is left over once the background jobs are sure to
+// get the rest of their shares.
+func (m *budgetManager) spend(host string, shares BudgetShares, job Job, budget object.Budget) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := budgetKey{host: host, resource: budget.Resource}
+	w, ok := m.windows[key]
+	if !ok || !m.now().Before(w.reset) {
+		w = &budgetWindow{reset: budget.Reset, spent: make(map[Job]int)}
+		m.windows[key] = w
+	}
+
+	cost := max(1, budget.Cost)
+	if w.spent[job]+cost <= allowance(shares, job, budget) {
+		w.spent[job] += cost
+		return nil
+	}
+
+	if job == JobInteractive {
+		owed := 0
+		for j := range shares {
+			if j != JobInteractive {
+				owed += max(0, allowance(shares, j, budget)-w.spent[j])
+			}
+		}
+		if budget.Remaining >= owed+cost {
+			w.spent[job] += cost
+			return nil
+		}
+	}
+
+	return &BudgetExhaustedError{Host: host, Job: job, Resource: budget.Resource, Reset: w.reset}
+}
+
+func allowance(shares BudgetShares, job Job, budget object.Budget) int {
+	return int(shares[job] * float64(budget.Limit))
+}
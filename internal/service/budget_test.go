@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/project/internal/service/mock_data"
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// budgetedDetails reports a fixed core budget and asks the admitter before every request.
+type budgetedDetails struct {
+	mock_data.MockGitDetails
+	budget object.Budget
+}
+
+func (d *budgetedDetails) Budget(resource string) (object.Budget, bool) {
+	return d.budget, resource == object.ResourceCore
+}
+
+func TestParseBudgetShares(t *testing.T) {
+	shares, err := ParseBudgetShares("search=20, commits=60,interactive=20")
+	require.NoError(t, err)
+	assert.Equal(t, BudgetShares{JobSearch: 0.2, JobCommits: 0.6, JobInteractive: 0.2}, shares)
+
+	_, err = ParseBudgetShares("search=50,commits=60")
+	assert.Error(t, err)
+	_, err = ParseBudgetShares("backfill=10")
+	assert.Error(t, err)
+}
+
+func TestBudgetDefersJobsPastTheirShare(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+	m := newBudgetManager()
+	shares := BudgetShares{JobSearch: 0.2, JobCommits: 0.6, JobInteractive: 0.2}
+	budget := object.Budget{Resource: object.ResourceCore, Limit: 10, Remaining: 10, Reset: reset}
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, m.spend("github.com", shares, JobSearch, budget))
+	}
+	err := m.spend("github.com", shares, JobSearch, budget)
+	var exhausted *BudgetExhaustedError
+	require.ErrorAs(t, err, &exhausted)
+	assert.Equal(t, JobSearch, exhausted.Job)
+	assert.Equal(t, reset, exhausted.Reset)
+
+	// the other jobs keep their shares
+	assert.NoError(t, m.spend("github.com", shares, JobCommits, budget))
+	// and other hosts have budgets of their own
+	assert.NoError(t, m.spend("ghe.example", shares, JobSearch, budget))
+}
+
+func TestBudgetInteractiveBorrowsUnusedQuota(t *testing.T) {
+	m := newBudgetManager()
+	shares := BudgetShares{JobCommits: 0.5, JobInteractive: 0.1}
+	budget := object.Budget{Resource: object.ResourceCore, Limit: 10, Remaining: 10, Reset: time.Now().Add(time.Hour)}
+
+	// the reserve of one request, then the four nobody has a share of
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, m.spend("github.com", shares, JobInteractive, budget))
+		budget.Remaining--
+	}
+	// the rest is owed to commits
+	assert.Error(t, m.spend("github.com", shares, JobInteractive, budget))
+	assert.NoError(t, m.spend("github.com", shares, JobCommits, budget))
+}
+
+func TestBudgetChargesGraphQLPoints(t *testing.T) {
+	m := newBudgetManager()
+	shares := BudgetShares{JobCommits: 0.5, JobInteractive: 0.1}
+	budget := object.Budget{Resource: object.ResourceGraphQL, Limit: 1000, Remaining: 1000, Reset: time.Now().Add(time.Hour), Cost: 200}
+
+	// a share of 500 points covers two queries of 200
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, m.spend("github.com", shares, JobCommits, budget))
+	}
+	assert.Error(t, m.spend("github.com", shares, JobCommits, budget))
+
+	// the interactive reserve of 100 points does not cover a single query; it
+	// borrows from what is left once the 100 points owed to commits are set aside
+	budget.Remaining = 600
+	assert.NoError(t, m.spend("github.com", shares, JobInteractive, budget))
+	budget.Remaining = 200
+	assert.Error(t, m.spend("github.com", shares, JobInteractive, budget))
+}
+
+func TestBudgetWindowResets(t *testing.T) {
+	now := time.Now()
+	m := newBudgetManager()
+	m.now = func() time.Time { return now }
+	shares := BudgetShares{JobSearch: 0.1}
+	budget := object.Budget{Resource: object.ResourceCore, Limit: 10, Remaining: 10, Reset: now.Add(time.Minute)}
+
+	assert.NoError(t, m.spend("github.com", shares, JobSearch, budget))
+	assert.Error(t, m.spend("github.com", shares, JobSearch, budget))
+
+	now = now.Add(time.Minute)
+	budget.Reset = now.Add(time.Hour)
+	assert.NoError(t, m.spend("github.com", shares, JobSearch, budget))
+}
+
+func TestSearchReposDefersExhaustedJob(t *testing.T) {
+	details := &budgetedDetails{budget: object.Budget{Resource: object.ResourceCore, Limit: 10, Remaining: 10, Reset: time.Now().Add(time.Hour)}}
+	details.SearchReposFunc = func(ctx context.Context, query object.SearchQuery, handle object.RepoPageFunc) error {
+		for {
+			if err := object.Admit(ctx, object.ResourceCore); err != nil {
+				return err
+			}
+		}
+	}
+	gitService := NewGitInfo(new(MockGitRepo), Provider{Name: "github", Host: "github.com", Details: details, BudgetShares: BudgetShares{JobSearch: 0.3}})
+
+	err := gitService.SearchRepos(WithJob(context.Background(), JobSearch), "", object.SearchQuery{Keywords: "go"}, nil)
+	var exhausted *BudgetExhaustedError
+	require.ErrorAs(t, err, &exhausted)
+	assert.Equal(t, "github.com", exhausted.Host)
+}
@@ -3,83 +3,262 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/project/internal/model"
 	"github.com/project/internal/repository"
 	"github.com/project/pkg/object"
 	"log"
+	"strings"
 	"sync"
 	"time"
 )
 
+// IGitInfo serves repositories from the providers it was built with. Methods
+// taking a host use the provider of that host; the empty host is the default
+// provider's.
 type IGitInfo interface {
-	SearchRepos(ctx context.Context, interest string) error
-	FetchRepo(ctx context.Context, name, repo string) (*model.Repository, error)
+	ResolveHost(host string) (string, error)
+	SearchRepos(ctx context.Context, host string, query object.SearchQuery, found RepoFoundFunc) error
+	ValidateSearchQuery(host string, query object.SearchQuery) error
+	ListOwnerRepos(ctx context.Context, owner model.TrackedOwner, found RepoFoundFunc, filtered RepoFilteredFunc) error
+	FetchRepo(ctx context.Context, host, owner, repo string) (*model.Repository, error)
 	UpdateRepo(ctx context.Context) error
-	GetCommit(ctx context.Context, name, repo string) ([]model.Commit, error)
+	TrackRepo(ctx context.Context, host, owner, name string, settings WatchSettings) (*model.Repository, error)
+	UntrackRepo(ctx context.Context, host, owner, name string) error
+	GetCommit(ctx context.Context, host, owner, repo string) (commits []model.Commit, stale bool, err error)
 	GetRepoByLanguage(ctx context.Context, language string) ([]model.Repository, error)
 	GetTopNRepoByStarCount(ctx context.Context, n int) ([]model.Repository, error)
+	RateLimits() []object.RateLimit
+}
+
+// RepoFoundFunc is called with the stored record of every repository a search finds.
+type RepoFoundFunc func(ctx context.Context, repo model.Repository) error
+
+// RepoFilteredFunc is called with every repository a listing found but left out
+// by the filters of the owner.
+type RepoFilteredFunc func(ctx context.Context, repo object.Repository) error
+
+const (
+	// recentCommitsLimit is the number of commits GetCommit returns to the caller.
+	recentCommitsLimit = 100
+	// defaultPollInterval is how often, in minutes, tracked repositories without
+	// an interval of their own are synced.
+	defaultPollInterval = 300
+	// defaultFreshnessTTL is how long, in minutes, a stored repository without a
+	// TTL of its own is served without asking the provider.
+	defaultFreshnessTTL = 10
+	// updateWorkers is the number of repositories UpdateRepo syncs concurrently.
+	updateWorkers = 3
+	// updatePageSize is the number of due repositories read from the database at
+	// a time, and the size of the batches sent to providers that batch requests.
+	updatePageSize = 25
+)
+
+// WatchSettings is the sync schedule of a repository on the watchlist.
+type WatchSettings struct {
+	// PollIntervalMinutes is how often the repository is synced; zero uses the default.
+	PollIntervalMinutes int
+	// FreshnessTTLMinutes is how long the stored record is served without asking
+	// the provider; zero uses the default.
+	FreshnessTTLMinutes int
+	Enabled             bool
 }
 
 type gitInfo struct {
-	repo       repository.IGitRepo
-	gitDetails object.GitDetails
+	repo repository.IGitRepo
+	// gitDetails serves defaultHost; providers holds every provider by host.
+	gitDetails  object.GitDetails
+	defaultHost string
+	providers   map[string]Provider
+	// budget splits the quota of every provider between the jobs using it.
+	budget *budgetManager
 }
 
-func NewGitInfo(repo repository.IGitRepo, gitDetails object.GitDetails) IGitInfo {
-	return gitInfo{repo: repo, gitDetails: gitDetails}
+// NewGitInfo serves repositories from the given providers, the first of which
+// is the default.
+func NewGitInfo(repo repository.IGitRepo, defaultProvider Provider, more ...Provider) IGitInfo {
+	g := gitInfo{
+		repo:        repo,
+		gitDetails:  defaultProvider.Details,
+		defaultHost: strings.ToLower(defaultProvider.Host),
+		providers:   make(map[string]Provider, len(more)+1),
+		budget:      newBudgetManager(),
+	}
+	for _, p := range append([]Provider{defaultProvider}, more...) {
+		p.Host = strings.ToLower(p.Host)
+		g.providers[p.Host] = p
+	}
+
+	return g
 }
 
-func (g gitInfo) SearchRepos(ctx context.Context, interest string) error {
-	var (
-		repoResp []object.Repository
-		rate     int64
-		err      error
-	)
+// SearchRepos stores every repository matching query, page by page as the
+// provider finds them, and hands each stored record to found when it is set.
+// Rate limits hit mid-search are waited out in place.
+func (g gitInfo) SearchRepos(ctx context.Context, host string, query object.SearchQuery, found RepoFoundFunc) error {
+	p, err := g.provider(host)
+	if err != nil {
+		return err
+	}
+
+	ctx = object.WithWaiter(g.budget.admitting(ctx, p), waitForReset)
+	storePage := g.storeRepos(ctx, p, nil, found)
+
 	for {
-		repoResp, rate, err = g.gitDetails.SearchRepos(ctx, interest)
+		err := p.Details.SearchRepos(ctx, query, storePage)
 		if err != nil {
-			if err.Error() == "rate_limit" {
-				time.Sleep(time.Duration(rate) * time.Minute)
+			if errors.Is(err, object.ErrNotModified) {
+				log.Printf("search results for %q unchanged, skipping", query.Keywords)
+				return nil
+			}
+			retry, waitErr := waitForReset(ctx, err)
+			if waitErr != nil {
+				return waitErr
+			}
+			if retry {
 				continue
 			}
+			if isDeferred(err) || isUnavailable(err) {
+				return err
+			}
 			log.Printf("error fetching repo, err %v", err)
 			return errors.New("unable to process")
 		}
 
-		break
+		return nil
 	}
+}
 
-	for _, rr := range repoResp {
-		err = g.upsertRepo(ctx, rr)
-		if err != nil {
-			log.Printf("error processing repository %s/%s, error: %v", rr.Owner, rr.Name, err)
+// ListOwnerRepos stores every repository of the tracked owner that passes its
+// fork and archive filters, handing each stored record to found, and hands the
+// ones the filters leave out to filtered. A missing owner is reported as a
+// NotFoundError.
+func (g gitInfo) ListOwnerRepos(ctx context.Context, owner model.TrackedOwner, found RepoFoundFunc, filtered RepoFilteredFunc) error {
+	p, err := g.provider(owner.Host)
+	if err != nil {
+		return err
+	}
+
+	ctx = object.WithWaiter(g.budget.admitting(ctx, p), waitForReset)
+	keep := func(rr object.Repository) bool {
+		return (owner.IncludeForks || !rr.Fork) && (owner.IncludeArchived || !rr.Archived)
+	}
+	storePage := g.storeRepos(ctx, p, keep, found)
+	page := func(repos []object.Repository) error {
+		for _, rr := range repos {
+			if keep(rr) {
+				continue
+			}
+			if err := filtered(ctx, rr); err != nil {
+				return err
+			}
+		}
+		return storePage(repos)
+	}
+
+	list := p.Details.ListUserRepos
+	if owner.Kind == model.OwnerKindOrg {
+		list = p.Details.ListOrgRepos
+	}
+
+	if err := list(ctx, owner.Login, page); err != nil {
+		var notFound *object.NotFoundError
+		if errors.As(err, &notFound) || isDeferred(err) || isUnavailable(err) {
+			return err
 		}
+		log.Printf("error listing repositories of %s, err %v", owner.Login, err)
+		return errors.New("unable to process")
 	}
 
 	return nil
 }
 
-func (g gitInfo) FetchRepo(ctx context.Context, owner, repo string) (*model.Repository, error) {
-	resp, err := g.repo.GetRepo(ctx, owner, repo)
+// storeRepos returns a page handler storing the repositories keep accepts, or
+// all of them when keep is nil, and handing each stored record to found. A
+// repository that cannot be stored or handed over stops the listing, so that
+// callers never mistake an incomplete listing for a complete one.
+func (g gitInfo) storeRepos(ctx context.Context, p Provider, keep func(object.Repository) bool, found RepoFoundFunc) object.RepoPageFunc {
+	return func(repos []object.Repository) error {
+		for _, rr := range repos {
+			if keep != nil && !keep(rr) {
+				continue
+			}
+
+			stored, err := g.upsertRepo(ctx, p, rr)
+			if err != nil {
+				return fmt.Errorf("storing repository %s/%s: %w", rr.Owner, rr.Name, err)
+			}
+			if found == nil {
+				continue
+			}
+			if err := found(ctx, stored); err != nil {
+				return fmt.Errorf("processing repository %s/%s: %w", rr.Owner, rr.Name, err)
+			}
+		}
+		return nil
+	}
+}
+
+func (g gitInfo) FetchRepo(ctx context.Context, host, owner, repo string) (*model.Repository, error) {
+	p, err := g.provider(host)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.fetchRepo(ctx, p, owner, repo)
+}
+
+// fetchRepo reads owner/repo through the stored record: a record fetched within
+// its freshness TTL is served as it is, marked cached, unless ctx asks for a
+// refresh; otherwise it is refreshed from p. While p is unavailable the stored
+// record is returned as it is, marked stale.
+func (g gitInfo) fetchRepo(ctx context.Context, p Provider, owner, repo string) (*model.Repository, error) {
+	resp, err := g.repo.GetRepo(ctx, p.Host, owner, repo)
 	if err != nil {
 		log.Printf("error fetching repo, err %v", err)
 		return nil, errors.New("unable to process")
 	}
+	if resp != nil && !isRefresh(ctx) && isFresh(*resp, time.Now()) {
+		resp.Cached = true
+		return resp, nil
+	}
 
-	gitDetail := g.gitDetails
+	ctx = g.budget.admitting(ctx, p)
+	gitDetail := p.Details
 
-	var (
-		repoResp *object.Repository
-		rate     int64
-	)
+	var repoResp *object.Repository
 	for {
-		repoResp, rate, err = gitDetail.FetchRepo(ctx, owner, repo)
+		repoResp, err = gitDetail.FetchRepo(ctx, owner, repo)
 		if err != nil {
-			if err.Error() == "rate_limit" {
-				time.Sleep(time.Duration(rate) * time.Minute)
+			if errors.Is(err, object.ErrNotModified) {
+				if resp != nil {
+					now := time.Now()
+					if err := g.repo.MarkRepoFetched(ctx, resp.ID, now); err != nil {
+						log.Printf("error recording fetch of %s/%s, err %v", owner, repo, err)
+					}
+					resp.FetchedAt = &now
+					return resp, nil
+				}
+				// nothing stored to fall back on, ask for the full resource
+				ctx = object.WithForceRefresh(ctx)
 				continue
 			}
+			retry, waitErr := waitForReset(ctx, err)
+			if waitErr != nil {
+				return nil, waitErr
+			}
+			if retry {
+				continue
+			}
+			if isUnavailable(err) && resp != nil {
+				resp.Stale = true
+				return resp, nil
+			}
+			var notFound *object.NotFoundError
+			if errors.As(err, &notFound) || isDeferred(err) || isUnavailable(err) {
+				return nil, err
+			}
 			log.Printf("error fetching repo, err %v", err)
 			return nil, errors.New("unable to process")
 		}
@@ -87,25 +266,15 @@ func (g gitInfo) FetchRepo(ctx context.Context, owner, repo string) (*model.Repo
 		break
 	}
 
-	payload := model.Repository{
-		ID:              uuid.New(),
-		Name:            repoResp.Name,
-		Owner:           owner,
-		Description:     repoResp.Description,
-		URL:             repoResp.URL,
-		Language:        repoResp.Language,
-		ForksCount:      repoResp.ForksCount,
-		StarsCount:      repoResp.StarsCount,
-		OpenIssuesCount: repoResp.OpenIssuesCount,
-		WatchersCount:   repoResp.WatchersCount,
-		CreatedAt:       repoResp.CreatedAt,
-		UpdatedAt:       repoResp.UpdatedAt,
-	}
+	payload := repositoryRecord(uuid.New(), p, owner, *repoResp)
 
 	if resp != nil {
 		payload.ID = resp.ID
-		err = g.repo.UpdateRepoRecord(ctx, payload)
-		return resp, err
+		if err := g.repo.UpdateRepoRecord(ctx, payload); err != nil {
+			return nil, err
+		}
+		updated := withStoredState(payload, *resp)
+		return &updated, nil
 	}
 
 	err = g.repo.CreateRepoRecord(ctx, payload)
@@ -116,107 +285,350 @@ func (g gitInfo) FetchRepo(ctx context.Context, owner, repo string) (*model.Repo
 	return &payload, nil
 }
 
+// UpdateRepo syncs every tracked repository that is due for a poll. Repositories
+// of providers that batch requests are refreshed a page at a time, the others
+// one by one by a pool of workers. Once a job runs out of its share of the
+// quota of a host, or the host becomes unavailable, the remaining repositories
+// of that host stay due until the next pass.
 func (g gitInfo) UpdateRepo(ctx context.Context) error {
-	var wg sync.WaitGroup
-	repoChan := make(chan model.Repository, 10)
-	fetchMore := make(chan struct{}, 1)
-	done := make(chan struct{})
-	var maxPages int
-
-	// Start worker goroutines
-	for i := 0; i < 3; i++ {
+	ctx = WithJob(ctx, JobRefresh)
+
+	var (
+		wg       sync.WaitGroup
+		repoChan = make(chan model.Repository)
+		deferred sync.Map
+	)
+	deferHost := func(host string, err error) {
+		if _, seen := deferred.LoadOrStore(host, true); !seen {
+			log.Printf("deferring sync of %s repositories: %v", host, err)
+		}
+	}
+	isHostDeferred := func(host string) bool {
+		_, ok := deferred.Load(host)
+		return ok
+	}
+
+	for i := 0; i < updateWorkers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for repo := range repoChan {
-				_, err := g.GetCommit(ctx, repo.Owner, repo.Name)
+				if isHostDeferred(repo.Host) {
+					continue
+				}
+				p, err := g.provider(repo.Host)
 				if err != nil {
-					log.Printf("Error fetching commit: %v", err)
+					log.Printf("skipping %s/%s: %v", repo.Owner, repo.Name, err)
+					continue
 				}
-				if len(repoChan) == 0 {
-					fetchMore <- struct{}{}
+				if _, err := g.syncCommits(ctx, p, repo.Owner, repo.Name); err != nil {
+					if isDeferred(err) || isUnavailable(err) {
+						deferHost(repo.Host, err)
+						continue
+					}
+					log.Printf("Error fetching commit: %v", err)
+					continue
 				}
+				g.markSynced(ctx, repo)
 			}
 		}()
 	}
 
-	sendRepos := func(page int) {
-		repos, totalPage, err := g.repo.GetRepos(ctx, 10, page)
+	err := g.eachDueRepo(ctx, updatePageSize, func(repos []model.Repository) {
+		for host, hosted := range byHost(repos) {
+			if isHostDeferred(host) {
+				continue
+			}
+			p, err := g.provider(host)
+			if err == nil {
+				if batcher, ok := p.Details.(object.BatchFetcher); ok {
+					if err := g.syncBatch(ctx, p, batcher, hosted); err != nil {
+						deferHost(host, err)
+					}
+					continue
+				}
+			}
+
+			for _, repo := range hosted {
+				select {
+				case repoChan <- repo:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	})
+
+	close(repoChan)
+	wg.Wait()
+
+	return err
+}
+
+// eachDueRepo hands the tracked repositories due for a sync to handle, size at a
+// time. Pages are keyed on the repository id, so repositories marked as synced
+// along the way do not shift the pages still to come.
+func (g gitInfo) eachDueRepo(ctx context.Context, size int, handle func([]model.Repository)) error {
+	now := time.Now()
+	after := uuid.Nil
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		repos, err := g.repo.GetDueRepos(ctx, now, defaultPollInterval, after, size)
 		if err != nil {
 			log.Printf("Error fetching repos: %v", err)
-			close(done)
-			return
+			return errors.New("unable to process")
+		}
+		if len(repos) == 0 {
+			return nil
 		}
 
-		mutex := sync.Mutex{}
-		mutex.Lock()
-		maxPages = int(totalPage)
-		mutex.Unlock()
+		handle(repos)
+		after = repos[len(repos)-1].ID
+	}
+}
 
-		for _, repo := range repos {
-			repoChan <- repo
-		}
+func (g gitInfo) markSynced(ctx context.Context, repo model.Repository) {
+	if err := g.repo.MarkRepoSynced(ctx, repo.ID, time.Now()); err != nil {
+		log.Printf("error recording sync of %s/%s, err %v", repo.Owner, repo.Name, err)
 	}
+}
 
-	// Send initial batch of repositories
-	go sendRepos(1)
+// TrackRepo puts owner/name on the watchlist, storing the repository first when
+// it is not known yet.
+func (g gitInfo) TrackRepo(ctx context.Context, host, owner, name string, settings WatchSettings) (*model.Repository, error) {
+	if settings.PollIntervalMinutes < 0 {
+		return nil, &ValidationError{Message: "poll_interval_minutes must not be negative"}
+	}
+	if settings.FreshnessTTLMinutes < 0 {
+		return nil, &ValidationError{Message: "freshness_ttl_minutes must not be negative"}
+	}
 
-	// Monitor for fetchMore signals and send the next page of repositories
-	go func() {
-		page := 2
-		for {
-			select {
-			case <-fetchMore:
-				if page > maxPages {
-					close(done)
-					return
-				}
-				sendRepos(page)
-				page++
-			case <-done:
-				return
-			}
-		}
-	}()
+	repo, err := g.FetchRepo(ctx, host, owner, name)
+	if err != nil {
+		return nil, err
+	}
 
-	// Wait for all goroutines to finish
-	wg.Wait()
-	close(repoChan)
-	close(fetchMore)
+	if err := g.repo.WatchRepo(ctx, repo.ID, settings.PollIntervalMinutes, settings.FreshnessTTLMinutes, settings.Enabled); err != nil {
+		log.Printf("error watching %s/%s, err %v", owner, name, err)
+		return nil, errors.New("unable to process")
+	}
+
+	repo.Watched = true
+	repo.Tracked = true
+	repo.PollIntervalMinutes = settings.PollIntervalMinutes
+	repo.FreshnessTTLMinutes = settings.FreshnessTTLMinutes
+	repo.SyncEnabled = settings.Enabled
+	return repo, nil
+}
+
+// UntrackRepo takes owner/name off the watchlist. Repositories an interest
+// still refers to keep being synced.
+func (g gitInfo) UntrackRepo(ctx context.Context, host, owner, name string) error {
+	p, err := g.provider(host)
+	if err != nil {
+		return err
+	}
+
+	repo, err := g.repo.GetRepo(ctx, p.Host, owner, name)
+	if err != nil {
+		log.Printf("error fetching repo, err %v", err)
+		return errors.New("unable to process")
+	}
+	if repo == nil {
+		return &object.NotFoundError{Resource: fmt.Sprintf("repository %s/%s", owner, name)}
+	}
+
+	if err := g.repo.UnwatchRepo(ctx, repo.ID); err != nil {
+		log.Printf("error unwatching %s/%s, err %v", owner, name, err)
+		return errors.New("unable to process")
+	}
 
 	return nil
 }
 
-func (g gitInfo) GetCommit(ctx context.Context, name, repo string) ([]model.Commit, error) {
-	repoResp, err := g.FetchRepo(ctx, name, repo)
+// GetCommit syncs the commit history of owner/repo and returns the most recent
+// commits. While the provider is unavailable the stored commits of a known
+// repository are returned instead, reported as stale.
+func (g gitInfo) GetCommit(ctx context.Context, host, name, repo string) ([]model.Commit, bool, error) {
+	p, err := g.provider(host)
+	if err != nil {
+		return nil, false, err
+	}
+
+	stale := false
+	repoResp, err := g.syncCommits(ctx, p, name, repo)
+	if err != nil {
+		if !isUnavailable(err) {
+			return nil, false, err
+		}
+		stored, getErr := g.repo.GetRepo(ctx, p.Host, name, repo)
+		if getErr != nil {
+			log.Printf("error fetching repo, err %v", getErr)
+			return nil, false, errors.New("unable to process")
+		}
+		if stored == nil {
+			return nil, false, err
+		}
+		repoResp, stale = stored, true
+	}
+
+	commits, err := g.repo.GetCommits(ctx, repoResp.ID, recentCommitsLimit)
+	return commits, stale, err
+}
+
+// syncCommits refreshes the repository record and persists the commits made since
+// the repository's sync cursor.
+func (g gitInfo) syncCommits(ctx context.Context, p Provider, name, repo string) (*model.Repository, error) {
+	repoResp, err := g.fetchRepo(ctx, p, name, repo)
 	if err != nil {
 		return nil, err
 	}
 
-	gitDetail := g.gitDetails
+	writer, err := g.newCommitWriter(ctx, repoResp.ID)
+	if err != nil {
+		log.Printf("error fetching commit cursor for %s/%s, err %v", name, repo, err)
+		return nil, errors.New("unable to process")
+	}
 
-	var (
-		commitResp []model.Commit
-		rate       int64
-	)
+	if err := g.syncHistory(ctx, p, name, repo, writer); err != nil {
+		return nil, err
+	}
+
+	return repoResp, nil
+}
+
+// syncHistory pages through the history newer than the writer's cursor, persisting
+// each page as it arrives so large histories are never held in memory, and
+// advances the cursor once the history has been read to the end. A gap left by
+// an earlier sync that stopped at the page cap is backfilled first. Background
+// syncs spend the share of JobCommits.
+func (g gitInfo) syncHistory(ctx context.Context, p Provider, name, repo string, writer *commitWriter) error {
+	if jobOf(ctx) != JobInteractive {
+		ctx = WithJob(ctx, JobCommits)
+	}
+	ctx = g.budget.admitting(ctx, p)
+
+	for {
+		truncated, err := g.fetchHistory(ctx, p, name, repo, writer)
+		if err != nil {
+			return err
+		}
+
+		closed, err := writer.saveCursor(ctx, truncated)
+		if err != nil {
+			log.Printf("error saving commit cursor for %s/%s, err %v", name, repo, err)
+			return errors.New("unable to process")
+		}
+		// once the gap is closed, go on with the commits made above it
+		if !closed {
+			return nil
+		}
+	}
+}
+
+// fetchHistory persists the part of the history the writer asks for. It
+// reports truncated when the provider stopped at its page cap.
+func (g gitInfo) fetchHistory(ctx context.Context, p Provider, name, repo string, writer *commitWriter) (truncated bool, err error) {
+	if writer.backfilling() {
+		// an unchanged first page says nothing about the older pages left unread
+		ctx = object.WithForceRefresh(ctx)
+	}
 
 	for {
-		commits, callrate, err := gitDetail.FetchCommits(ctx, name, repo)
+		err := p.Details.FetchCommits(ctx, name, repo, writer.options(), writer.pageFunc(ctx))
 		if err != nil {
-			if err.Error() == "rate_limit" {
-				time.Sleep(time.Duration(rate) * time.Minute)
+			if errors.Is(err, object.ErrTruncated) {
+				return true, nil
+			}
+			if errors.Is(err, object.ErrNotModified) {
+				return false, nil
+			}
+			retry, waitErr := waitForReset(ctx, err)
+			if waitErr != nil {
+				return false, waitErr
+			}
+			if retry {
 				continue
 			}
-			log.Printf("error fetching repo, err %v", err)
-			return nil, errors.New("unable to process")
+			if isDeferred(err) || isUnavailable(err) {
+				return false, err
+			}
+			log.Printf("error fetching commits, err %v", err)
+			return false, errors.New("unable to process")
 		}
 
-		rate = callrate
+		return false, nil
+	}
+}
+
+// commitWriter persists pages of commits for one repository and keeps track of
+// the newest and oldest commits written, so the sync cursor can be moved
+// afterwards.
+type commitWriter struct {
+	repo   repository.IGitRepo
+	repoID uuid.UUID
+	cursor model.CommitCursor
+	// newest and oldest are the commits written since the cursor was last saved.
+	written        bool
+	newestSHA      string
+	newest, oldest time.Time
+}
+
+func (g gitInfo) newCommitWriter(ctx context.Context, repoID uuid.UUID) (*commitWriter, error) {
+	cursor, err := g.repo.GetCommitCursor(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &commitWriter{repo: g.repo, repoID: repoID}
+	if cursor != nil {
+		w.cursor = *cursor
+	}
+	return w, nil
+}
+
+// backfilling reports whether the writer is filling the gap below BackfillUntil.
+func (w *commitWriter) backfilling() bool {
+	return w.cursor.BackfillUntil != nil
+}
 
+// options narrows the history to the commits committed since the cursor, and
+// before the gap when one is left.
+func (w *commitWriter) options() object.CommitOptions {
+	opts := object.CommitOptions{Since: w.cursor.LastCommitDate}
+	if w.backfilling() {
+		opts.Until = *w.cursor.BackfillUntil
+	}
+
+	return opts
+}
+
+func (w *commitWriter) pageFunc(ctx context.Context) object.CommitPageFunc {
+	return func(commits []object.Commit) error {
+		commitResp := make([]model.Commit, 0, len(commits))
 		for _, commit := range commits {
+			// since is inclusive, so the cursor commit comes back; commits sharing
+			// its date may be new, and the upsert absorbs any other overlap
+			if commit.SHA == w.cursor.LastSHA {
+				continue
+			}
+			committedAt := commitTime(commit)
+
+			if !w.written || committedAt.After(w.newest) {
+				w.newestSHA = commit.SHA
+				w.newest = committedAt
+			}
+			if !w.written || committedAt.Before(w.oldest) {
+				w.oldest = committedAt
+			}
+			w.written = true
+
 			commitResp = append(commitResp, model.Commit{
 				ID:          uuid.New(),
-				RepoID:      repoResp.ID,
+				RepoID:      w.repoID,
 				SHA:         commit.SHA,
 				AuthorEmail: commit.AuthorEmail,
 				AuthorName:  commit.AuthorName,
@@ -225,15 +637,57 @@ func (g gitInfo) GetCommit(ctx context.Context, name, repo string) ([]model.Comm
 			})
 		}
 
-		break
+		if len(commitResp) == 0 {
+			return nil
+		}
+
+		return w.repo.UpsertCommitRecords(ctx, commitResp)
 	}
+}
 
-	err = g.repo.CreateCommitRecord(ctx, commitResp)
-	if err != nil {
-		return nil, err
+// saveCursor moves the cursor past the commits written. A history read to the
+// end moves it to the newest commit, or to the commit pending above the gap it
+// closed, which it reports. A truncated history leaves the cursor in place and
+// the gap down to the oldest commit written for later syncs to backfill.
+func (w *commitWriter) saveCursor(ctx context.Context, truncated bool) (closed bool, err error) {
+	cursor := w.cursor
+	switch {
+	case w.backfilling() && truncated:
+		if !w.written || !w.oldest.Before(*cursor.BackfillUntil) {
+			return false, nil
+		}
+		oldest := w.oldest
+		cursor.BackfillUntil = &oldest
+	case w.backfilling():
+		cursor.LastSHA, cursor.LastCommitDate = cursor.PendingSHA, cursor.PendingCommitDate
+		cursor.BackfillUntil, cursor.PendingSHA, cursor.PendingCommitDate = nil, "", time.Time{}
+		closed = true
+	case !w.written:
+		return false, nil
+	case truncated:
+		oldest := w.oldest
+		cursor.BackfillUntil = &oldest
+		cursor.PendingSHA, cursor.PendingCommitDate = w.newestSHA, w.newest
+	default:
+		cursor.LastSHA, cursor.LastCommitDate = w.newestSHA, w.newest
+	}
+
+	cursor.RepoID = w.repoID
+	if err := w.repo.SaveCommitCursor(ctx, cursor); err != nil {
+		return false, err
 	}
+	w.cursor = cursor
+	w.written = false
+	return closed, nil
+}
 
-	return commitResp, nil
+// commitTime is the committer date of commit, or its author date for providers
+// reporting a single date.
+func commitTime(commit object.Commit) time.Time {
+	if commit.CommittedAt.IsZero() {
+		return commit.Date
+	}
+	return commit.CommittedAt
 }
 
 func (g gitInfo) GetRepoByLanguage(ctx context.Context, language string) ([]model.Repository, error) {
@@ -244,38 +698,123 @@ func (g gitInfo) GetTopNRepoByStarCount(ctx context.Context, n int) ([]model.Rep
 	return g.repo.GetTopNRepoByStarCount(ctx, n)
 }
 
-func (g gitInfo) upsertRepo(ctx context.Context, rr object.Repository) error {
-	repo, err := g.repo.GetRepo(ctx, rr.Name, rr.Owner)
+// RateLimits reports the per-credential quota of every provider that tracks one.
+func (g gitInfo) RateLimits() []object.RateLimit {
+	var limits []object.RateLimit
+	for _, p := range g.allProviders() {
+		reporter, ok := p.Details.(object.RateLimitReporter)
+		if !ok {
+			continue
+		}
+
+		for _, limit := range reporter.RateLimits() {
+			limit.Host = p.Host
+			limits = append(limits, limit)
+		}
+	}
+
+	return limits
+}
+
+// ValidateSearchQuery rejects queries the provider of host would not accept,
+// when it can tell.
+func (g gitInfo) ValidateSearchQuery(host string, query object.SearchQuery) error {
+	p, err := g.provider(host)
 	if err != nil {
 		return err
 	}
 
+	validator, ok := p.Details.(object.SearchQueryValidator)
+	if !ok {
+		return nil
+	}
+
+	return validator.ValidateSearchQuery(query)
+}
+
+func (g gitInfo) upsertRepo(ctx context.Context, p Provider, rr object.Repository) (model.Repository, error) {
+	repo, err := g.repo.GetRepo(ctx, p.Host, rr.Owner, rr.Name)
+	if err != nil {
+		return model.Repository{}, err
+	}
+
 	if repo != nil {
-		err = g.repo.UpdateRepoRecord(ctx, *repo)
+		data := repositoryRecord(repo.ID, p, repo.Owner, rr)
+		err = g.repo.UpdateRepoRecord(ctx, data)
 		if err != nil {
 			log.Printf("error updating record with id: %s, error: %v", repo.ID, err)
-			return err
-		}
-	} else {
-		data := model.Repository{
-			ID:              uuid.New(),
-			Name:            rr.Name,
-			Owner:           rr.Owner,
-			Description:     rr.Description,
-			URL:             rr.URL,
-			Language:        rr.Language,
-			ForksCount:      rr.ForksCount,
-			StarsCount:      rr.StarsCount,
-			OpenIssuesCount: rr.OpenIssuesCount,
-			WatchersCount:   rr.WatchersCount,
-			CreatedAt:       rr.CreatedAt,
-			UpdatedAt:       rr.UpdatedAt,
-		}
-		err = g.repo.CreateRepoRecord(ctx, data)
-		if err != nil {
-			log.Printf("error creating record, error: %v", err)
-			return err
+			return model.Repository{}, err
 		}
+		return withStoredState(data, *repo), nil
 	}
-	return nil
+
+	data := repositoryRecord(uuid.New(), p, rr.Owner, rr)
+	err = g.repo.CreateRepoRecord(ctx, data)
+	if err != nil {
+		log.Printf("error creating record, error: %v", err)
+		return model.Repository{}, err
+	}
+	return data, nil
+}
+
+// repositoryRecord maps data of provider p, fetched just now, onto the stored
+// record with the given id.
+func repositoryRecord(id uuid.UUID, p Provider, owner string, rr object.Repository) model.Repository {
+	fetchedAt := time.Now()
+	return model.Repository{
+		ID:              id,
+		Provider:        p.Name,
+		Host:            p.Host,
+		Name:            rr.Name,
+		Owner:           owner,
+		Description:     rr.Description,
+		URL:             rr.URL,
+		Language:        rr.Language,
+		ForksCount:      rr.ForksCount,
+		StarsCount:      rr.StarsCount,
+		OpenIssuesCount: rr.OpenIssuesCount,
+		WatchersCount:   rr.WatchersCount,
+		CreatedAt:       rr.CreatedAt,
+		UpdatedAt:       rr.UpdatedAt,
+		FetchedAt:       &fetchedAt,
+	}
+}
+
+// withStoredState carries the tracking state of the stored record over to the
+// refreshed record, which only holds what the provider returned, so callers
+// see the record as it is stored after the update.
+func withStoredState(record, stored model.Repository) model.Repository {
+	record.ID = stored.ID
+	record.Tracked = stored.Tracked
+	record.Watched = stored.Watched
+	record.PollIntervalMinutes = stored.PollIntervalMinutes
+	record.SyncEnabled = stored.SyncEnabled
+	record.LastSyncedAt = stored.LastSyncedAt
+	record.FreshnessTTLMinutes = stored.FreshnessTTLMinutes
+	return record
+}
+
+// isFresh reports whether repo was fetched within its freshness TTL at now.
+func isFresh(repo model.Repository, now time.Time) bool {
+	if repo.FetchedAt == nil {
+		return false
+	}
+	ttl := repo.FreshnessTTLMinutes
+	if ttl == 0 {
+		ttl = defaultFreshnessTTL
+	}
+	return now.Before(repo.FetchedAt.Add(time.Duration(ttl) * time.Minute))
+}
+
+type refreshKey struct{}
+
+// WithRefresh makes reads through ctx ask the provider even for repositories
+// whose stored record is still fresh.
+func WithRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshKey{}, true)
+}
+
+func isRefresh(ctx context.Context) bool {
+	refresh, _ := ctx.Value(refreshKey{}).(bool)
+	return refresh
 }
@@ -15,12 +15,28 @@ import (
 // Mock repository
 type MockGitRepo struct {
 	mock.Mock
+	// stored is what GetRepo returns, a new record when nil
+	stored *model.Repository
 }
 
-func (m *MockGitRepo) CreateCommitRecord(ctx context.Context, commit []model.Commit) error {
+func (m *MockGitRepo) UpsertCommitRecords(ctx context.Context, commit []model.Commit) error {
 	return m.Called(ctx, commit).Error(0)
 }
 
+func (m *MockGitRepo) GetCommits(ctx context.Context, repoID uuid.UUID, limit int) ([]model.Commit, error) {
+	args := m.Called(ctx, repoID, limit)
+	return args.Get(0).([]model.Commit), args.Error(1)
+}
+
+func (m *MockGitRepo) GetCommitCursor(ctx context.Context, repoID uuid.UUID) (*model.CommitCursor, error) {
+	args := m.Called(ctx, repoID)
+	return args.Get(0).(*model.CommitCursor), args.Error(1)
+}
+
+func (m *MockGitRepo) SaveCommitCursor(ctx context.Context, cursor model.CommitCursor) error {
+	return m.Called(ctx, cursor).Error(0)
+}
+
 func (m *MockGitRepo) GetReposByLanguage(ctx context.Context, s string) ([]model.Repository, error) {
 	args := m.Called(ctx, s)
 	return args.Get(0).([]model.Repository), args.Error(1)
@@ -31,12 +47,32 @@ func (m *MockGitRepo) GetTopNRepoByStarCount(ctx context.Context, i int) ([]mode
 	return args.Get(0).([]model.Repository), args.Error(1)
 }
 
-func (m *MockGitRepo) GetRepos(ctx context.Context, limit, page int) ([]model.Repository, int64, error) {
-	args := m.Called(ctx, limit, page)
-	return args.Get(0).([]model.Repository), args.Get(1).(int64), args.Error(2)
+func (m *MockGitRepo) GetDueRepos(ctx context.Context, now time.Time, defaultInterval int, after uuid.UUID, limit int) ([]model.Repository, error) {
+	args := m.Called(ctx, now, defaultInterval, after, limit)
+	return args.Get(0).([]model.Repository), args.Error(1)
+}
+
+func (m *MockGitRepo) MarkRepoSynced(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return m.Called(ctx, id, at).Error(0)
+}
+
+func (m *MockGitRepo) MarkRepoFetched(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return m.Called(ctx, id, at).Error(0)
+}
+
+func (m *MockGitRepo) WatchRepo(ctx context.Context, id uuid.UUID, pollIntervalMinutes, freshnessTTLMinutes int, enabled bool) error {
+	return m.Called(ctx, id, pollIntervalMinutes, freshnessTTLMinutes, enabled).Error(0)
+}
+
+func (m *MockGitRepo) UnwatchRepo(ctx context.Context, id uuid.UUID) error {
+	return m.Called(ctx, id).Error(0)
 }
 
-func (m *MockGitRepo) GetRepo(ctx context.Context, owner, repo string) (*model.Repository, error) {
+func (m *MockGitRepo) GetRepo(ctx context.Context, host, owner, repo string) (*model.Repository, error) {
+	if m.stored != nil {
+		stored := *m.stored
+		return &stored, nil
+	}
 	return &model.Repository{
 		ID: uuid.New(),
 	}, nil
@@ -55,15 +91,15 @@ type MockGitDetails struct {
 	mock.Mock
 }
 
-func (m *MockGitDetails) FetchRepo(ctx context.Context, owner, repo string) (*object.Repository, int64, error) {
+func (m *MockGitDetails) FetchRepo(ctx context.Context, owner, repo string) (*object.Repository, error) {
 	return &object.Repository{
 		Name: repo,
-	}, 1, nil
+	}, nil
 }
 
-func (m *MockGitDetails) FetchCommits(ctx context.Context, owner, repo string) ([]object.Commit, int64, error) {
-	args := m.Called(ctx, owner, repo)
-	return args.Get(0).([]object.Commit), args.Get(1).(int64), args.Error(2)
+func (m *MockGitDetails) FetchCommits(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error {
+	args := m.Called(ctx, owner, repo, opts, handle)
+	return args.Error(0)
 }
 
 // Test FetchRepo method
@@ -71,7 +107,7 @@ func TestFetchRepo(t *testing.T) {
 	mockRepo := new(MockGitRepo)
 	mockGitDetails := new(MockGitDetails)
 	mockDetails := &mock_data.MockGitDetails{
-		FetchRepoFunc: func(ctx context.Context, owner, repo string) (*object.Repository, int64, error) {
+		FetchRepoFunc: func(ctx context.Context, owner, repo string) (*object.Repository, error) {
 			return &object.Repository{
 				Name:        repo,
 				Description: "A sample repository",
@@ -79,7 +115,7 @@ func TestFetchRepo(t *testing.T) {
 				Language:    "Go",
 				ForksCount:  10,
 				StarsCount:  100,
-			}, 0, nil
+			}, nil
 		},
 	}
 	gitService := gitInfo{repo: mockRepo, gitDetails: mockDetails}
@@ -101,9 +137,291 @@ func TestFetchRepo(t *testing.T) {
 	}
 
 	mockRepo.On("GetRepo", ctx, owner, repo).Return(model.Repository{ID: uuid.New()}, nil)
-	mockGitDetails.On("FetchRepo", ctx, owner, repo).Return(repoResp, int64(1), nil)
+	mockGitDetails.On("FetchRepo", ctx, owner, repo).Return(repoResp, nil)
 	mockRepo.On("CreateRepoRecord", ctx, mock.AnythingOfType("model.Repository")).Return(nil)
 
-	_, err := gitService.FetchRepo(ctx, owner, repo)
+	_, err := gitService.FetchRepo(ctx, "", owner, repo)
+	assert.NoError(t, err)
+}
+
+// Test GetCommit resumes from the cursor and persists every page handed over by the provider
+func TestGetCommitPersistsEachPage(t *testing.T) {
+	mockRepo := new(MockGitRepo)
+	cursorDate := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	var gotOpts object.CommitOptions
+	mockDetails := &mock_data.MockGitDetails{
+		FetchRepoFunc: func(ctx context.Context, owner, repo string) (*object.Repository, error) {
+			return &object.Repository{Name: repo}, nil
+		},
+		FetchCommitsFunc: func(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error {
+			gotOpts = opts
+			pages := [][]object.Commit{
+				{{SHA: "c", Date: cursorDate.Add(2 * time.Hour)}, {SHA: "b", Date: cursorDate.Add(time.Hour)}},
+				{{SHA: "a", Date: cursorDate}},
+			}
+			for _, page := range pages {
+				if err := handle(page); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	gitService := gitInfo{repo: mockRepo, gitDetails: mockDetails}
+
+	ctx := context.Background()
+	mockRepo.On("GetCommitCursor", ctx, mock.AnythingOfType("uuid.UUID")).Return(&model.CommitCursor{LastSHA: "a", LastCommitDate: cursorDate}, nil)
+	mockRepo.On("UpsertCommitRecords", ctx, mock.AnythingOfType("[]model.Commit")).Return(nil)
+	mockRepo.On("SaveCommitCursor", ctx, mock.MatchedBy(func(c model.CommitCursor) bool {
+		return c.LastSHA == "c" && c.LastCommitDate.Equal(cursorDate.Add(2*time.Hour))
+	})).Return(nil)
+	mockRepo.On("GetCommits", ctx, mock.AnythingOfType("uuid.UUID"), recentCommitsLimit).Return([]model.Commit{{SHA: "c"}}, nil)
+
+	commits, _, err := gitService.GetCommit(ctx, "", "owner", "repo")
+	assert.NoError(t, err)
+	assert.Len(t, commits, 1)
+	assert.True(t, gotOpts.Since.Equal(cursorDate))
+	// the page holding only the cursor commit is not written again
+	mockRepo.AssertNumberOfCalls(t, "UpsertCommitRecords", 1)
+	mockRepo.AssertCalled(t, "SaveCommitCursor", ctx, mock.Anything)
+}
+
+// Test commits made in the same second as the cursor commit are still stored
+func TestGetCommitKeepsCommitsSharingCursorDate(t *testing.T) {
+	mockRepo := new(MockGitRepo)
+	cursorDate := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	mockDetails := &mock_data.MockGitDetails{
+		FetchRepoFunc: func(ctx context.Context, owner, repo string) (*object.Repository, error) {
+			return &object.Repository{Name: repo}, nil
+		},
+		FetchCommitsFunc: func(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error {
+			return handle([]object.Commit{{SHA: "c", Date: cursorDate}, {SHA: "b", Date: cursorDate}, {SHA: "a", Date: cursorDate}})
+		},
+	}
+	gitService := gitInfo{repo: mockRepo, gitDetails: mockDetails}
+
+	ctx := context.Background()
+	var written []string
+	mockRepo.On("GetCommitCursor", ctx, mock.AnythingOfType("uuid.UUID")).Return(&model.CommitCursor{LastSHA: "a", LastCommitDate: cursorDate}, nil)
+	mockRepo.On("UpsertCommitRecords", ctx, mock.AnythingOfType("[]model.Commit")).Run(func(args mock.Arguments) {
+		for _, c := range args.Get(1).([]model.Commit) {
+			written = append(written, c.SHA)
+		}
+	}).Return(nil)
+	mockRepo.On("SaveCommitCursor", ctx, mock.MatchedBy(func(c model.CommitCursor) bool {
+		return c.LastSHA == "c" && c.LastCommitDate.Equal(cursorDate)
+	})).Return(nil)
+	mockRepo.On("GetCommits", ctx, mock.AnythingOfType("uuid.UUID"), recentCommitsLimit).Return([]model.Commit{{SHA: "c"}}, nil)
+
+	_, _, err := gitService.GetCommit(ctx, "", "owner", "repo")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c", "b"}, written)
+	mockRepo.AssertExpectations(t)
+}
+
+// Test a history truncated at the page cap keeps the cursor in place and is backfilled by the next sync
+func TestGetCommitBackfillsTruncatedHistory(t *testing.T) {
+	mockRepo := new(MockGitRepo)
+	t0 := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time { return t0.Add(time.Duration(hours) * time.Hour) }
+
+	var calls []object.CommitOptions
+	mockDetails := &mock_data.MockGitDetails{
+		FetchRepoFunc: func(ctx context.Context, owner, repo string) (*object.Repository, error) {
+			return &object.Repository{Name: repo}, nil
+		},
+		FetchCommitsFunc: func(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error {
+			calls = append(calls, opts)
+			switch len(calls) {
+			case 1:
+				// c2 was authored before the cursor but rebased after it
+				if err := handle([]object.Commit{{SHA: "c3", Date: at(3), CommittedAt: at(3)}, {SHA: "c2", Date: at(-5), CommittedAt: at(2)}}); err != nil {
+					return err
+				}
+				return object.ErrTruncated
+			case 2:
+				return handle([]object.Commit{{SHA: "c2", Date: at(-5), CommittedAt: at(2)}, {SHA: "c1", Date: at(1), CommittedAt: at(1)}})
+			default:
+				return handle([]object.Commit{{SHA: "c4", Date: at(4), CommittedAt: at(4)}, {SHA: "c3", Date: at(3), CommittedAt: at(3)}})
+			}
+		},
+	}
+	gitService := gitInfo{repo: mockRepo, gitDetails: mockDetails}
+
+	ctx := context.Background()
+	cursor := &model.CommitCursor{LastSHA: "c0", LastCommitDate: t0}
+	var saved []model.CommitCursor
+	mockRepo.On("GetCommitCursor", ctx, mock.AnythingOfType("uuid.UUID")).Return(cursor, nil)
+	mockRepo.On("UpsertCommitRecords", mock.Anything, mock.AnythingOfType("[]model.Commit")).Return(nil)
+	mockRepo.On("SaveCommitCursor", mock.Anything, mock.AnythingOfType("model.CommitCursor")).Run(func(args mock.Arguments) {
+		saved = append(saved, args.Get(1).(model.CommitCursor))
+		*cursor = saved[len(saved)-1]
+	}).Return(nil)
+	mockRepo.On("GetCommits", ctx, mock.AnythingOfType("uuid.UUID"), recentCommitsLimit).Return([]model.Commit{}, nil)
+
+	// the first sync stops at the cap: the cursor stays, the gap is recorded
+	_, _, err := gitService.GetCommit(ctx, "", "owner", "repo")
+	assert.NoError(t, err)
+	assert.Len(t, saved, 1)
+	assert.True(t, saved[0].LastCommitDate.Equal(t0))
+	assert.True(t, saved[0].BackfillUntil.Equal(at(2)))
+	assert.Equal(t, "c3", saved[0].PendingSHA)
+
+	// the next one backfills the gap, then goes on above it
+	_, _, err = gitService.GetCommit(ctx, "", "owner", "repo")
+	assert.NoError(t, err)
+	assert.Len(t, calls, 3)
+	assert.True(t, calls[1].Since.Equal(t0))
+	assert.True(t, calls[1].Until.Equal(at(2)))
+	assert.True(t, calls[2].Since.Equal(at(3)))
+	assert.True(t, calls[2].Until.IsZero())
+	assert.Len(t, saved, 3)
+	assert.Equal(t, "c3", saved[1].LastSHA)
+	assert.Nil(t, saved[1].BackfillUntil)
+	assert.Equal(t, "c4", saved[2].LastSHA)
+	assert.True(t, saved[2].LastCommitDate.Equal(at(4)))
+}
+
+// Test FetchRepo serves the stored record when GitHub reports no change, and records the fetch
+func TestFetchRepoNotModified(t *testing.T) {
+	stored := &model.Repository{ID: uuid.New(), Owner: "owner", Name: "repo"}
+	mockRepo := &MockGitRepo{stored: stored}
+	mockDetails := &mock_data.MockGitDetails{
+		FetchRepoFunc: func(ctx context.Context, owner, repo string) (*object.Repository, error) {
+			return nil, object.ErrNotModified
+		},
+	}
+	gitService := gitInfo{repo: mockRepo, gitDetails: mockDetails}
+	mockRepo.On("MarkRepoFetched", mock.Anything, stored.ID, mock.AnythingOfType("time.Time")).Return(nil).Once()
+
+	resp, err := gitService.FetchRepo(context.Background(), "", "owner", "repo")
+	assert.NoError(t, err)
+	assert.Equal(t, stored.ID, resp.ID)
+	assert.NotNil(t, resp.FetchedAt)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "CreateRepoRecord", mock.Anything, mock.Anything)
+}
+
+// Test FetchRepo revalidates a stored record past its TTL instead of serving it from the cache
+func TestFetchRepoRevalidatesExpiredRecord(t *testing.T) {
+	fetchedAt := time.Now().Add(-time.Duration(defaultFreshnessTTL+1) * time.Minute)
+	stored := &model.Repository{ID: uuid.New(), Owner: "owner", Name: "repo", FetchedAt: &fetchedAt}
+	mockRepo := &MockGitRepo{stored: stored}
+	var fetches int
+	mockDetails := &mock_data.MockGitDetails{
+		FetchRepoFunc: func(ctx context.Context, owner, repo string) (*object.Repository, error) {
+			fetches++
+			return nil, object.ErrNotModified
+		},
+	}
+	gitService := gitInfo{repo: mockRepo, gitDetails: mockDetails}
+	mockRepo.On("MarkRepoFetched", mock.Anything, stored.ID, mock.AnythingOfType("time.Time")).Return(nil).Once()
+
+	resp, err := gitService.FetchRepo(context.Background(), "", "owner", "repo")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fetches)
+	assert.False(t, resp.Cached)
+	assert.True(t, resp.FetchedAt.After(fetchedAt))
+	mockRepo.AssertExpectations(t)
+}
+
+// Test UpdateRepo syncs every due repository, paging by id, and records each sync
+func TestUpdateRepoSyncsDueRepos(t *testing.T) {
+	mockRepo := new(MockGitRepo)
+	mockDetails := &mock_data.MockGitDetails{
+		FetchRepoFunc: func(ctx context.Context, owner, repo string) (*object.Repository, error) {
+			return nil, object.ErrNotModified
+		},
+		FetchCommitsFunc: func(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error {
+			return nil
+		},
+	}
+	gitService := gitInfo{repo: mockRepo, gitDetails: mockDetails}
+
+	page := make([]model.Repository, updatePageSize)
+	for i := range page {
+		page[i] = model.Repository{ID: uuid.New(), Owner: "owner", Name: "repo"}
+	}
+	last := page[len(page)-1].ID
+
+	// UpdateRepo runs with its own job, so the context passed on is derived from ctx
+	ctx := mock.Anything
+	mockRepo.On("GetDueRepos", ctx, mock.Anything, defaultPollInterval, uuid.Nil, updatePageSize).Return(page, nil).Once()
+	mockRepo.On("GetDueRepos", ctx, mock.Anything, defaultPollInterval, last, updatePageSize).Return([]model.Repository{}, nil).Once()
+	mockRepo.On("GetCommitCursor", ctx, mock.AnythingOfType("uuid.UUID")).Return((*model.CommitCursor)(nil), nil)
+	mockRepo.On("MarkRepoSynced", ctx, mock.AnythingOfType("uuid.UUID"), mock.Anything).Return(nil)
+	mockRepo.On("MarkRepoFetched", ctx, mock.AnythingOfType("uuid.UUID"), mock.Anything).Return(nil)
+
+	assert.NoError(t, gitService.UpdateRepo(context.Background()))
+	mockRepo.AssertNumberOfCalls(t, "MarkRepoSynced", updatePageSize)
+	mockRepo.AssertExpectations(t)
+}
+
+// Test FetchRepo and GetCommit serve stored data, marked stale, while the provider is unavailable
+func TestServesStaleDataWhileUnavailable(t *testing.T) {
+	mockRepo := new(MockGitRepo)
+	unavailable := &object.UnavailableError{Host: "api.github.com", RetryAfter: 30 * time.Second}
+	mockDetails := &mock_data.MockGitDetails{
+		FetchRepoFunc: func(ctx context.Context, owner, repo string) (*object.Repository, error) {
+			return nil, unavailable
+		},
+		FetchCommitsFunc: func(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error {
+			return unavailable
+		},
+	}
+	gitService := gitInfo{repo: mockRepo, gitDetails: mockDetails}
+
+	ctx := context.Background()
+	repo, err := gitService.FetchRepo(ctx, "", "owner", "repo")
+	assert.NoError(t, err)
+	assert.True(t, repo.Stale)
+	mockRepo.AssertNotCalled(t, "UpdateRepoRecord", mock.Anything, mock.Anything)
+
+	mockRepo.On("GetCommitCursor", ctx, mock.AnythingOfType("uuid.UUID")).Return((*model.CommitCursor)(nil), nil)
+	mockRepo.On("GetCommits", ctx, mock.AnythingOfType("uuid.UUID"), recentCommitsLimit).Return([]model.Commit{{SHA: "a"}}, nil)
+
+	commits, stale, err := gitService.GetCommit(ctx, "", "owner", "repo")
+	assert.NoError(t, err)
+	assert.True(t, stale)
+	assert.Len(t, commits, 1)
+}
+
+// Test FetchRepo serves a fresh stored record without asking the provider, unless asked to refresh
+func TestFetchRepoReadsThroughFreshRecord(t *testing.T) {
+	fetchedAt := time.Now().Add(-time.Minute)
+	stored := &model.Repository{ID: uuid.New(), Owner: "owner", Name: "repo", Watched: true, Tracked: true, StarsCount: 1, FetchedAt: &fetchedAt}
+	mockRepo := &MockGitRepo{stored: stored}
+	var fetches int
+	mockDetails := &mock_data.MockGitDetails{
+		FetchRepoFunc: func(ctx context.Context, owner, repo string) (*object.Repository, error) {
+			fetches++
+			return &object.Repository{Owner: owner, Name: repo, StarsCount: 2}, nil
+		},
+	}
+	gitService := gitInfo{repo: mockRepo, gitDetails: mockDetails}
+
+	repo, err := gitService.FetchRepo(context.Background(), "", "owner", "repo")
+	assert.NoError(t, err)
+	assert.True(t, repo.Cached)
+	assert.Equal(t, 1, repo.StarsCount)
+	assert.Zero(t, fetches)
+
+	// a refresh returns the updated record, tracking state included
+	repo, err = gitService.FetchRepo(WithRefresh(context.Background()), "", "owner", "repo")
+	assert.NoError(t, err)
+	assert.False(t, repo.Cached)
+	assert.Equal(t, 2, repo.StarsCount)
+	assert.Equal(t, stored.ID, repo.ID)
+	assert.True(t, repo.Watched)
+	assert.True(t, repo.FetchedAt.After(fetchedAt))
+	assert.Equal(t, 1, fetches)
+
+	// past its TTL the record is refreshed
+	expired := time.Now().Add(-2 * time.Minute)
+	mockRepo.stored.FetchedAt = &expired
+	mockRepo.stored.FreshnessTTLMinutes = 1
+	repo, err = gitService.FetchRepo(context.Background(), "", "owner", "repo")
 	assert.NoError(t, err)
+	assert.False(t, repo.Cached)
+	assert.Equal(t, 2, fetches)
 }
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project/internal/model"
+	"github.com/project/internal/repository"
+	"github.com/project/pkg/object"
+)
+
+// defaultInterestInterval is how often an interest is searched when no interval is given.
+const defaultInterestInterval = 60
+
+// ValidationError reports a request the service refuses to act on.
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// ConflictError reports a request clashing with a record already stored.
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+type IInterest interface {
+	CreateInterest(ctx context.Context, interest model.Interest) (*model.Interest, error)
+	UpdateInterest(ctx context.Context, interest model.Interest) (*model.Interest, error)
+	DeleteInterest(ctx context.Context, id uuid.UUID) error
+	GetInterest(ctx context.Context, id uuid.UUID) (*model.Interest, error)
+	GetInterests(ctx context.Context) ([]model.Interest, error)
+	GetInterestRepos(ctx context.Context, id uuid.UUID) ([]model.Repository, error)
+	RunDue(ctx context.Context) error
+}
+
+type interests struct {
+	repo repository.IInterestRepo
+	git  IGitInfo
+	now  func() time.Time
+}
+
+func NewInterests(repo repository.IInterestRepo, git IGitInfo) IInterest {
+	return interests{repo: repo, git: git, now: time.Now}
+}
+
+func (s interests) CreateInterest(ctx context.Context, interest model.Interest) (*model.Interest, error) {
+	interest.ID = uuid.New()
+	interest.LastRunAt = nil
+	if err := s.validate(&interest); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateInterest(ctx, interest); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, nameTaken(interest)
+		}
+		log.Printf("error creating interest %q, err %v", interest.Name, err)
+		return nil, errors.New("unable to process")
+	}
+
+	return &interest, nil
+}
+
+// UpdateInterest replaces the name, host, query, schedule and enabled state of
+// an existing interest.
+func (s interests) UpdateInterest(ctx context.Context, interest model.Interest) (*model.Interest, error) {
+	current, err := s.GetInterest(ctx, interest.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validate(&interest); err != nil {
+		return nil, err
+	}
+
+	current.Name = interest.Name
+	current.Host = interest.Host
+	current.Query = interest.Query
+	current.IntervalMinutes = interest.IntervalMinutes
+	current.Enabled = interest.Enabled
+	if err := s.repo.UpdateInterest(ctx, *current); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, nameTaken(*current)
+		}
+		log.Printf("error updating interest %s, err %v", interest.ID, err)
+		return nil, errors.New("unable to process")
+	}
+
+	return current, nil
+}
+
+// DeleteInterest removes the interest; the repositories only it had discovered
+// are no longer kept in sync.
+func (s interests) DeleteInterest(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.GetInterest(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteInterest(ctx, id); err != nil {
+		log.Printf("error deleting interest %s, err %v", id, err)
+		return errors.New("unable to process")
+	}
+
+	return nil
+}
+
+func (s interests) GetInterest(ctx context.Context, id uuid.UUID) (*model.Interest, error) {
+	interest, err := s.repo.GetInterest(ctx, id)
+	if err != nil {
+		log.Printf("error fetching interest %s, err %v", id, err)
+		return nil, errors.New("unable to process")
+	}
+	if interest == nil {
+		return nil, &object.NotFoundError{Resource: fmt.Sprintf("interest %s", id)}
+	}
+
+	return interest, nil
+}
+
+func (s interests) GetInterests(ctx context.Context) ([]model.Interest, error) {
+	return s.repo.GetInterests(ctx)
+}
+
+func (s interests) GetInterestRepos(ctx context.Context, id uuid.UUID) ([]model.Repository, error) {
+	if _, err := s.GetInterest(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetInterestRepos(ctx, id)
+}
+
+// RunDue searches every enabled interest whose interval has passed since its
+// last run, linking the repositories found to it. The searches spend the
+// JobSearch share of the quota.
+func (s interests) RunDue(ctx context.Context) error {
+	all, err := s.repo.GetInterests(ctx)
+	if err != nil {
+		log.Printf("error fetching interests, err %v", err)
+		return errors.New("unable to process")
+	}
+
+	for _, interest := range all {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		now := s.now()
+		if !interest.Due(now) {
+			continue
+		}
+
+		log.Printf("searching repositories of interest %q", interest.Name)
+		link := func(ctx context.Context, repo model.Repository) error {
+			return s.repo.LinkRepo(ctx, interest.ID, repo.ID)
+		}
+		if err := s.git.SearchRepos(WithJob(ctx, JobSearch), interest.Host, interest.Query, link); err != nil {
+			log.Printf("error searching interest %q, err %v", interest.Name, err)
+			continue
+		}
+
+		if err := s.repo.MarkInterestRun(ctx, interest.ID, now); err != nil {
+			log.Printf("error recording run of interest %q, err %v", interest.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func nameTaken(interest model.Interest) error {
+	return &ConflictError{Message: fmt.Sprintf("an interest named %q already exists", interest.Name)}
+}
+
+// validate fills in the default schedule and checks the interest can be searched.
+func (s interests) validate(interest *model.Interest) error {
+	interest.Name = strings.TrimSpace(interest.Name)
+	if interest.Name == "" {
+		return &ValidationError{Message: "interest name is required"}
+	}
+
+	if interest.IntervalMinutes == 0 {
+		interest.IntervalMinutes = defaultInterestInterval
+	}
+	if interest.IntervalMinutes < 0 {
+		return &ValidationError{Message: "interval_minutes must be positive"}
+	}
+
+	host, err := s.git.ResolveHost(interest.Host)
+	if err != nil {
+		return err
+	}
+	interest.Host = host
+
+	if err := s.git.ValidateSearchQuery(interest.Host, interest.Query); err != nil {
+		return &ValidationError{Message: err.Error()}
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project/internal/model"
+	"github.com/project/internal/repository"
+	"github.com/project/internal/service/mock_data"
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockInterestRepo struct {
+	mock.Mock
+}
+
+func (m *MockInterestRepo) CreateInterest(ctx context.Context, interest model.Interest) error {
+	return m.Called(ctx, interest).Error(0)
+}
+
+func (m *MockInterestRepo) UpdateInterest(ctx context.Context, interest model.Interest) error {
+	return m.Called(ctx, interest).Error(0)
+}
+
+func (m *MockInterestRepo) DeleteInterest(ctx context.Context, id uuid.UUID) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockInterestRepo) GetInterest(ctx context.Context, id uuid.UUID) (*model.Interest, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*model.Interest), args.Error(1)
+}
+
+func (m *MockInterestRepo) GetInterests(ctx context.Context) ([]model.Interest, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]model.Interest), args.Error(1)
+}
+
+func (m *MockInterestRepo) MarkInterestRun(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return m.Called(ctx, id, at).Error(0)
+}
+
+func (m *MockInterestRepo) LinkRepo(ctx context.Context, interestID, repoID uuid.UUID) error {
+	return m.Called(ctx, interestID, repoID).Error(0)
+}
+
+func (m *MockInterestRepo) GetInterestRepos(ctx context.Context, id uuid.UUID) ([]model.Repository, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]model.Repository), args.Error(1)
+}
+
+// Test RunDue only searches enabled interests whose interval has passed, and links what they find
+func TestRunDueSearchesDueInterests(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	recently := now.Add(-10 * time.Minute)
+	longAgo := now.Add(-2 * time.Hour)
+
+	due := model.Interest{ID: uuid.New(), Name: "crypto", Query: object.SearchQuery{Keywords: "crypto"}, IntervalMinutes: 60, Enabled: true, LastRunAt: &longAgo}
+	notDue := model.Interest{ID: uuid.New(), Name: "rust", Query: object.SearchQuery{Keywords: "rust"}, IntervalMinutes: 60, Enabled: true, LastRunAt: &recently}
+	disabled := model.Interest{ID: uuid.New(), Name: "java", Query: object.SearchQuery{Keywords: "java"}, IntervalMinutes: 60}
+
+	var searched []string
+	details := &mock_data.MockGitDetails{
+		SearchReposFunc: func(ctx context.Context, query object.SearchQuery, handle object.RepoPageFunc) error {
+			searched = append(searched, query.Keywords)
+			return handle([]object.Repository{{Name: "wallet", Owner: "acme"}})
+		},
+	}
+
+	gitRepo := new(MockGitRepo)
+	gitRepo.On("UpdateRepoRecord", mock.Anything, mock.Anything).Return(nil)
+
+	interestRepo := new(MockInterestRepo)
+	ctx := context.Background()
+	interestRepo.On("GetInterests", ctx).Return([]model.Interest{due, notDue, disabled}, nil)
+	interestRepo.On("LinkRepo", mock.Anything, due.ID, mock.AnythingOfType("uuid.UUID")).Return(nil).Once()
+	interestRepo.On("MarkInterestRun", ctx, due.ID, now).Return(nil).Once()
+
+	svc := interests{repo: interestRepo, git: gitInfo{repo: gitRepo, gitDetails: details}, now: func() time.Time { return now }}
+
+	assert.NoError(t, svc.RunDue(ctx))
+	assert.Equal(t, []string{"crypto"}, searched)
+	interestRepo.AssertExpectations(t)
+}
+
+// Test creating an interest under a name already in use is reported as a conflict
+func TestCreateInterestConflict(t *testing.T) {
+	interestRepo := new(MockInterestRepo)
+	interestRepo.On("CreateInterest", mock.Anything, mock.AnythingOfType("model.Interest")).Return(repository.ErrDuplicate)
+
+	git := NewGitInfo(new(MockGitRepo), Provider{Name: "github", Host: "github.com", Details: &mock_data.MockGitDetails{}})
+	svc := NewInterests(interestRepo, git)
+
+	_, err := svc.CreateInterest(context.Background(), model.Interest{Name: "crypto", Query: object.SearchQuery{Keywords: "crypto"}})
+	var conflict *ConflictError
+	assert.ErrorAs(t, err, &conflict)
+}
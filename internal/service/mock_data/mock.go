@@ -6,14 +6,29 @@ import (
 )
 
 type MockGitDetails struct {
-	FetchRepoFunc    func(ctx context.Context, owner, repo string) (*object.Repository, int64, error)
-	FetchCommitsFunc func(ctx context.Context, owner, repo string) ([]object.Commit, int64, error)
+	SearchReposFunc   func(ctx context.Context, query object.SearchQuery, handle object.RepoPageFunc) error
+	FetchRepoFunc     func(ctx context.Context, owner, repo string) (*object.Repository, error)
+	FetchCommitsFunc  func(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error
+	ListOrgReposFunc  func(ctx context.Context, org string, handle object.RepoPageFunc) error
+	ListUserReposFunc func(ctx context.Context, user string, handle object.RepoPageFunc) error
 }
 
-func (m *MockGitDetails) FetchRepo(ctx context.Context, owner, repo string) (*object.Repository, int64, error) {
+func (m *MockGitDetails) SearchRepos(ctx context.Context, query object.SearchQuery, handle object.RepoPageFunc) error {
+	return m.SearchReposFunc(ctx, query, handle)
+}
+
+func (m *MockGitDetails) FetchRepo(ctx context.Context, owner, repo string) (*object.Repository, error) {
 	return m.FetchRepoFunc(ctx, owner, repo)
 }
 
-func (m *MockGitDetails) FetchCommits(ctx context.Context, owner, repo string) ([]object.Commit, int64, error) {
-	return m.FetchCommitsFunc(ctx, owner, repo)
+func (m *MockGitDetails) FetchCommits(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error {
+	return m.FetchCommitsFunc(ctx, owner, repo, opts, handle)
+}
+
+func (m *MockGitDetails) ListOrgRepos(ctx context.Context, org string, handle object.RepoPageFunc) error {
+	return m.ListOrgReposFunc(ctx, org, handle)
+}
+
+func (m *MockGitDetails) ListUserRepos(ctx context.Context, user string, handle object.RepoPageFunc) error {
+	return m.ListUserReposFunc(ctx, user, handle)
 }
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project/internal/model"
+	"github.com/project/internal/repository"
+	"github.com/project/pkg/object"
+)
+
+// defaultReconcileInterval is how often, in minutes, a tracked owner is listed
+// when no interval is given.
+const defaultReconcileInterval = 360
+
+type IOwners interface {
+	CreateTrackedOwner(ctx context.Context, owner model.TrackedOwner) (*model.TrackedOwner, error)
+	UpdateTrackedOwner(ctx context.Context, owner model.TrackedOwner) (*model.TrackedOwner, error)
+	DeleteTrackedOwner(ctx context.Context, id uuid.UUID) error
+	GetTrackedOwner(ctx context.Context, id uuid.UUID) (*model.TrackedOwner, error)
+	GetTrackedOwners(ctx context.Context) ([]model.TrackedOwner, error)
+	GetOwnerRepos(ctx context.Context, id uuid.UUID) ([]model.OwnedRepository, error)
+	RunDue(ctx context.Context) error
+}
+
+type owners struct {
+	repo repository.IOwnerRepo
+	git  IGitInfo
+	now  func() time.Time
+}
+
+func NewOwners(repo repository.IOwnerRepo, git IGitInfo) IOwners {
+	return owners{repo: repo, git: git, now: time.Now}
+}
+
+func (s owners) CreateTrackedOwner(ctx context.Context, owner model.TrackedOwner) (*model.TrackedOwner, error) {
+	owner.ID = uuid.New()
+	owner.LastRunAt = nil
+	owner.Login = strings.TrimSpace(owner.Login)
+	if owner.Login == "" {
+		return nil, &ValidationError{Message: "login is required"}
+	}
+	if owner.Kind != model.OwnerKindOrg && owner.Kind != model.OwnerKindUser {
+		return nil, &ValidationError{Message: fmt.Sprintf("kind must be %q or %q", model.OwnerKindOrg, model.OwnerKindUser)}
+	}
+	host, err := s.git.ResolveHost(owner.Host)
+	if err != nil {
+		return nil, err
+	}
+	owner.Host = host
+	if err := validateOwnerSchedule(&owner); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateTrackedOwner(ctx, owner); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, &ConflictError{Message: fmt.Sprintf("%s %s is already tracked on %s", owner.Kind, owner.Login, owner.Host)}
+		}
+		log.Printf("error creating tracked owner %s, err %v", owner.Login, err)
+		return nil, errors.New("unable to process")
+	}
+
+	return &owner, nil
+}
+
+// UpdateTrackedOwner replaces the filters, schedule and enabled state of a
+// tracked owner. The host, login and kind cannot change.
+func (s owners) UpdateTrackedOwner(ctx context.Context, owner model.TrackedOwner) (*model.TrackedOwner, error) {
+	current, err := s.GetTrackedOwner(ctx, owner.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateOwnerSchedule(&owner); err != nil {
+		return nil, err
+	}
+
+	current.IncludeForks = owner.IncludeForks
+	current.IncludeArchived = owner.IncludeArchived
+	current.IntervalMinutes = owner.IntervalMinutes
+	current.Enabled = owner.Enabled
+	if err := s.repo.UpdateTrackedOwner(ctx, *current); err != nil {
+		log.Printf("error updating tracked owner %s, err %v", owner.ID, err)
+		return nil, errors.New("unable to process")
+	}
+
+	return current, nil
+}
+
+// DeleteTrackedOwner stops tracking the owner; its repositories are no longer
+// kept in sync unless something else tracks them.
+func (s owners) DeleteTrackedOwner(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.GetTrackedOwner(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteTrackedOwner(ctx, id); err != nil {
+		log.Printf("error deleting tracked owner %s, err %v", id, err)
+		return errors.New("unable to process")
+	}
+
+	return nil
+}
+
+func (s owners) GetTrackedOwner(ctx context.Context, id uuid.UUID) (*model.TrackedOwner, error) {
+	owner, err := s.repo.GetTrackedOwner(ctx, id)
+	if err != nil {
+		log.Printf("error fetching tracked owner %s, err %v", id, err)
+		return nil, errors.New("unable to process")
+	}
+	if owner == nil {
+		return nil, &object.NotFoundError{Resource: fmt.Sprintf("tracked owner %s", id)}
+	}
+
+	return owner, nil
+}
+
+func (s owners) GetTrackedOwners(ctx context.Context) ([]model.TrackedOwner, error) {
+	return s.repo.GetTrackedOwners(ctx)
+}
+
+func (s owners) GetOwnerRepos(ctx context.Context, id uuid.UUID) ([]model.OwnedRepository, error) {
+	if _, err := s.GetTrackedOwner(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetOwnerRepos(ctx, id)
+}
+
+// RunDue reconciles every enabled owner whose interval has passed since its
+// last run, spending the JobSearch share of the quota.
+func (s owners) RunDue(ctx context.Context) error {
+	all, err := s.repo.GetTrackedOwners(ctx)
+	if err != nil {
+		log.Printf("error fetching tracked owners, err %v", err)
+		return errors.New("unable to process")
+	}
+
+	for _, owner := range all {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		now := s.now()
+		if !owner.Due(now) {
+			continue
+		}
+
+		if err := s.reconcile(ctx, owner, now); err != nil {
+			log.Printf("error reconciling repositories of %s, err %v", owner.Login, err)
+			continue
+		}
+
+		if err := s.repo.MarkOwnerRun(ctx, owner.ID, now); err != nil {
+			log.Printf("error recording run of tracked owner %s, err %v", owner.Login, err)
+		}
+	}
+
+	return nil
+}
+
+// reconcile lists the repositories of owner, tracking the new ones, unlinks the
+// ones its filters now leave out and flags the ones it no longer lists as
+// missing. Nothing is unlinked or flagged unless the listing completed and
+// every repository was linked, and an owner that no longer exists lists nothing.
+func (s owners) reconcile(ctx context.Context, owner model.TrackedOwner, started time.Time) error {
+	log.Printf("reconciling repositories of %s %s on %s", owner.Kind, owner.Login, owner.Host)
+	// seen_at is compared against started, at the precision Postgres stores
+	started = started.Truncate(time.Microsecond)
+
+	link := func(ctx context.Context, repo model.Repository) error {
+		return s.repo.LinkOwnerRepo(ctx, owner.ID, repo.ID, started)
+	}
+	var filtered []object.RepoRef
+	filter := func(ctx context.Context, repo object.Repository) error {
+		filtered = append(filtered, object.RepoRef{Owner: repo.Owner, Name: repo.Name})
+		return nil
+	}
+	if err := s.git.ListOwnerRepos(WithJob(ctx, JobSearch), owner, link, filter); err != nil {
+		var notFound *object.NotFoundError
+		if !errors.As(err, &notFound) {
+			return err
+		}
+		log.Printf("%s %s no longer exists", owner.Kind, owner.Login)
+	}
+
+	// a fork or archived repository left out is still there, not missing
+	if len(filtered) > 0 {
+		unlinked, err := s.repo.UnlinkOwnerRepos(ctx, owner.ID, owner.Host, filtered)
+		if err != nil {
+			return err
+		}
+		if unlinked > 0 {
+			log.Printf("%d repositories of %s are left out by its filters, unlinked", unlinked, owner.Login)
+		}
+	}
+
+	flagged, err := s.repo.FlagMissingOwnerRepos(ctx, owner.ID, started)
+	if err != nil {
+		return err
+	}
+	if flagged > 0 {
+		log.Printf("%d repositories of %s are no longer listed, flagged as missing", flagged, owner.Login)
+	}
+
+	return nil
+}
+
+// validateOwnerSchedule fills in the default interval and rejects negative ones.
+func validateOwnerSchedule(owner *model.TrackedOwner) error {
+	if owner.IntervalMinutes == 0 {
+		owner.IntervalMinutes = defaultReconcileInterval
+	}
+	if owner.IntervalMinutes < 0 {
+		return &ValidationError{Message: "interval_minutes must be positive"}
+	}
+
+	return nil
+}
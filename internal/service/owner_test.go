@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/project/internal/model"
+	"github.com/project/internal/repository"
+	"github.com/project/internal/service/mock_data"
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOwnerRepo struct {
+	mock.Mock
+}
+
+func (m *MockOwnerRepo) CreateTrackedOwner(ctx context.Context, owner model.TrackedOwner) error {
+	return m.Called(ctx, owner).Error(0)
+}
+
+func (m *MockOwnerRepo) UpdateTrackedOwner(ctx context.Context, owner model.TrackedOwner) error {
+	return m.Called(ctx, owner).Error(0)
+}
+
+func (m *MockOwnerRepo) DeleteTrackedOwner(ctx context.Context, id uuid.UUID) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockOwnerRepo) GetTrackedOwner(ctx context.Context, id uuid.UUID) (*model.TrackedOwner, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*model.TrackedOwner), args.Error(1)
+}
+
+func (m *MockOwnerRepo) GetTrackedOwners(ctx context.Context) ([]model.TrackedOwner, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]model.TrackedOwner), args.Error(1)
+}
+
+func (m *MockOwnerRepo) MarkOwnerRun(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return m.Called(ctx, id, at).Error(0)
+}
+
+func (m *MockOwnerRepo) LinkOwnerRepo(ctx context.Context, ownerID, repoID uuid.UUID, seenAt time.Time) error {
+	return m.Called(ctx, ownerID, repoID, seenAt).Error(0)
+}
+
+func (m *MockOwnerRepo) FlagMissingOwnerRepos(ctx context.Context, ownerID uuid.UUID, since time.Time) (int64, error) {
+	args := m.Called(ctx, ownerID, since)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockOwnerRepo) UnlinkOwnerRepos(ctx context.Context, ownerID uuid.UUID, host string, repos []object.RepoRef) (int64, error) {
+	args := m.Called(ctx, ownerID, host, repos)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockOwnerRepo) GetOwnerRepos(ctx context.Context, id uuid.UUID) ([]model.OwnedRepository, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]model.OwnedRepository), args.Error(1)
+}
+
+// Test RunDue links the listed repositories that pass the owner's filters, unlinks the filtered ones and flags the unlisted ones
+func TestRunDueReconcilesOwners(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	org := model.TrackedOwner{ID: uuid.New(), Login: "acme", Kind: model.OwnerKindOrg, IntervalMinutes: 60, Enabled: true}
+	gone := model.TrackedOwner{ID: uuid.New(), Login: "ghost", Kind: model.OwnerKindUser, IntervalMinutes: 60, Enabled: true}
+
+	gitRepo := new(MockGitRepo)
+	gitRepo.On("UpdateRepoRecord", mock.Anything, mock.Anything).Return(nil)
+
+	details := &mock_data.MockGitDetails{
+		ListOrgReposFunc: func(ctx context.Context, login string, handle object.RepoPageFunc) error {
+			return handle([]object.Repository{
+				{Owner: login, Name: "api"},
+				{Owner: login, Name: "fork", Fork: true},
+				{Owner: login, Name: "legacy", Archived: true},
+			})
+		},
+		ListUserReposFunc: func(ctx context.Context, login string, handle object.RepoPageFunc) error {
+			return &object.NotFoundError{Resource: "user " + login}
+		},
+	}
+
+	ownerRepo := new(MockOwnerRepo)
+	ctx := context.Background()
+	ownerRepo.On("GetTrackedOwners", ctx).Return([]model.TrackedOwner{org, gone}, nil)
+	// forks and archived repositories are filtered out, only api is linked
+	ownerRepo.On("LinkOwnerRepo", mock.Anything, org.ID, mock.AnythingOfType("uuid.UUID"), now).Return(nil).Once()
+	// and the others are unlinked rather than flagged as missing
+	ownerRepo.On("UnlinkOwnerRepos", ctx, org.ID, org.Host, []object.RepoRef{{Owner: "acme", Name: "fork"}, {Owner: "acme", Name: "legacy"}}).Return(int64(1), nil).Once()
+	ownerRepo.On("FlagMissingOwnerRepos", ctx, org.ID, now).Return(int64(0), nil).Once()
+	// the listing of an owner that no longer exists is empty, so all its repositories are flagged
+	ownerRepo.On("FlagMissingOwnerRepos", ctx, gone.ID, now).Return(int64(3), nil).Once()
+	ownerRepo.On("MarkOwnerRun", ctx, mock.AnythingOfType("uuid.UUID"), now).Return(nil).Twice()
+
+	svc := owners{repo: ownerRepo, git: gitInfo{repo: gitRepo, gitDetails: details}, now: func() time.Time { return now }}
+
+	assert.NoError(t, svc.RunDue(ctx))
+	ownerRepo.AssertExpectations(t)
+}
+
+// Test a repository that cannot be linked aborts the reconciliation before anything is flagged
+func TestReconcileAbortsWhenLinkingFails(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	org := model.TrackedOwner{ID: uuid.New(), Login: "acme", Kind: model.OwnerKindOrg, IntervalMinutes: 60, Enabled: true}
+
+	gitRepo := new(MockGitRepo)
+	gitRepo.On("UpdateRepoRecord", mock.Anything, mock.Anything).Return(nil)
+	details := &mock_data.MockGitDetails{
+		ListOrgReposFunc: func(ctx context.Context, login string, handle object.RepoPageFunc) error {
+			return handle([]object.Repository{{Owner: login, Name: "api"}, {Owner: login, Name: "web"}})
+		},
+	}
+
+	ownerRepo := new(MockOwnerRepo)
+	ctx := context.Background()
+	ownerRepo.On("GetTrackedOwners", ctx).Return([]model.TrackedOwner{org}, nil)
+	ownerRepo.On("LinkOwnerRepo", mock.Anything, org.ID, mock.AnythingOfType("uuid.UUID"), now).Return(errors.New("connection reset")).Once()
+
+	svc := owners{repo: ownerRepo, git: gitInfo{repo: gitRepo, gitDetails: details}, now: func() time.Time { return now }}
+
+	assert.NoError(t, svc.RunDue(ctx))
+	ownerRepo.AssertExpectations(t)
+	ownerRepo.AssertNotCalled(t, "FlagMissingOwnerRepos", mock.Anything, mock.Anything, mock.Anything)
+	// the owner stays due
+	ownerRepo.AssertNotCalled(t, "MarkOwnerRun", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Test tracking an owner twice is reported as a conflict
+func TestCreateTrackedOwnerConflict(t *testing.T) {
+	ownerRepo := new(MockOwnerRepo)
+	ownerRepo.On("CreateTrackedOwner", mock.Anything, mock.AnythingOfType("model.TrackedOwner")).Return(repository.ErrDuplicate)
+
+	svc := owners{repo: ownerRepo, git: gitInfo{defaultHost: "github.com"}, now: time.Now}
+
+	_, err := svc.CreateTrackedOwner(context.Background(), model.TrackedOwner{Login: "acme", Kind: model.OwnerKindOrg})
+	var conflict *ConflictError
+	assert.ErrorAs(t, err, &conflict)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/project/internal/model"
+	"github.com/project/pkg/object"
+)
+
+// Provider is a git host the service reads repositories from.
+type Provider struct {
+	// Name identifies the kind of host, such as github or gitlab.
+	Name string
+	// Host is the web host of the provider, such as github.com; it keeps
+	// repositories of different hosts with the same owner and name apart.
+	Host    string
+	Details object.GitDetails
+	// BudgetShares divides the quota of providers reporting a Budget between
+	// jobs; nil uses DefaultBudgetShares.
+	BudgetShares BudgetShares
+}
+
+// ResolveHost returns the host the provider of host is registered under, the
+// default host when host is empty.
+func (g gitInfo) ResolveHost(host string) (string, error) {
+	p, err := g.provider(host)
+	if err != nil {
+		return "", err
+	}
+
+	return p.Host, nil
+}
+
+// provider returns the provider serving host, the default one when host is empty.
+func (g gitInfo) provider(host string) (Provider, error) {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" {
+		host = g.defaultHost
+	}
+
+	if p, ok := g.providers[host]; ok {
+		return p, nil
+	}
+	if host == g.defaultHost {
+		return Provider{Host: g.defaultHost, Details: g.gitDetails}, nil
+	}
+
+	return Provider{}, &ValidationError{Message: fmt.Sprintf("unknown host %q", host)}
+}
+
+// allProviders returns every provider, ordered by host.
+func (g gitInfo) allProviders() []Provider {
+	if len(g.providers) == 0 {
+		return []Provider{{Host: g.defaultHost, Details: g.gitDetails}}
+	}
+
+	all := make([]Provider, 0, len(g.providers))
+	for _, p := range g.providers {
+		all = append(all, p)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Host < all[j].Host })
+
+	return all
+}
+
+// byHost groups repos by the host they were stored from.
+func byHost(repos []model.Repository) map[string][]model.Repository {
+	grouped := make(map[string][]model.Repository)
+	for _, repo := range repos {
+		grouped[repo.Host] = append(grouped[repo.Host], repo)
+	}
+
+	return grouped
+}
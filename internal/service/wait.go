@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+const (
+	// maxRateLimitWait caps a single wait for a rate-limit reset; GitHub windows
+	// never last longer, so anything beyond it means a bogus reset header.
+	maxRateLimitWait = time.Hour
+	// minRateLimitWait guards against resets that already passed due to clock skew.
+	minRateLimitWait = time.Second
+)
+
+// RateLimitedError is returned instead of waiting when the caller asked not to
+// block on rate limits, see WithoutWaiting.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return e.Err
+}
+
+// WithoutWaiting marks ctx as interactive: rate limits are reported straight
+// away as a RateLimitedError instead of being waited out.
+func WithoutWaiting(ctx context.Context) context.Context {
+	return object.WithoutWaiting(ctx)
+}
+
+// waitForReset blocks until the rate limit reported by err is lifted, so the
+// caller can retry. It reports retry == false for errors that are not rate
+// limits. The wait is capped at maxRateLimitWait and ends early with the
+// context's error when ctx is cancelled.
+func waitForReset(ctx context.Context, err error) (retry bool, waitErr error) {
+	delay, ok := retryDelay(err)
+	if !ok {
+		return false, nil
+	}
+
+	if delay < minRateLimitWait {
+		delay = minRateLimitWait
+	}
+	if delay > maxRateLimitWait {
+		delay = maxRateLimitWait
+	}
+
+	if !object.IsWaitingAllowed(ctx) {
+		return false, &RateLimitedError{RetryAfter: delay, Err: err}
+	}
+
+	log.Printf("rate limited (%v), waiting %s before retrying", err, delay.Round(time.Second))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// isUnavailable reports whether err is a provider held back by its circuit breaker.
+func isUnavailable(err error) bool {
+	var unavailable *object.UnavailableError
+	return errors.As(err, &unavailable)
+}
+
+// retryDelay reports how long to back off before retrying after a rate-limit error.
+func retryDelay(err error) (time.Duration, bool) {
+	var (
+		rateLimit *object.RateLimitError
+		secondary *object.SecondaryRateLimitError
+	)
+	switch {
+	case errors.As(err, &rateLimit):
+		return time.Until(rateLimit.Reset), true
+	case errors.As(err, &secondary):
+		return secondary.RetryAfter, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForResetIgnoresOtherErrors(t *testing.T) {
+	retry, err := waitForReset(context.Background(), &object.NotFoundError{Resource: "repo"})
+	assert.False(t, retry)
+	assert.NoError(t, err)
+}
+
+func TestWaitForResetWithoutWaiting(t *testing.T) {
+	ctx := WithoutWaiting(context.Background())
+	retry, err := waitForReset(ctx, &object.RateLimitError{Reset: time.Now().Add(10 * time.Minute)})
+	assert.False(t, retry)
+
+	var rateLimited *RateLimitedError
+	assert.True(t, errors.As(err, &rateLimited))
+	assert.InDelta(t, (10 * time.Minute).Seconds(), rateLimited.RetryAfter.Seconds(), 5)
+}
+
+func TestWaitForResetCapsAndHonoursCancellation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// a reset that is an epoch timestamp in the far future must not block forever
+	start := time.Now()
+	retry, err := waitForReset(ctx, &object.RateLimitError{Reset: time.Now().Add(100 * 365 * 24 * time.Hour)})
+	assert.False(t, retry)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
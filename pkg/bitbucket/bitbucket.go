@@ -0,0 +1,212 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/httpclient"
+	"github.com/project/pkg/object"
+)
+
+const (
+	// pageLen is the largest page size Bitbucket accepts for repositories and commits.
+	pageLen = 100
+	// maxCommitPages bounds a single FetchCommits call to 10,000 commits.
+	maxCommitPages = 100
+
+	defaultBaseURL = "https://api.bitbucket.org/2.0"
+)
+
+type bitbucket struct {
+	baseURL     string
+	client      *resty.Client
+	username    string
+	appPassword string
+}
+
+// WithAppPassword authenticates as username with an app password instead of
+// BITBUCKET_USERNAME and BITBUCKET_APP_PASSWORD.
+func WithAppPassword(username, appPassword string) httpclient.Option {
+	return func(o *httpclient.Options) {
+		o.Username = username
+		o.Token = appPassword
+	}
+}
+
+// NewBitbucket returns a GitDetails reading repositories from Bitbucket Cloud.
+// Workspaces take the place of GitHub's organizations and users, and the
+// workspace slug is a repository's owner. The 2.0 API at BITBUCKET_BASE_URL,
+// or api.bitbucket.org when that is unset, is read with the app password of
+// BITBUCKET_USERNAME in BITBUCKET_APP_PASSWORD unless opts say otherwise.
+func NewBitbucket(opts ...httpclient.Option) object.GitDetails {
+	o, client := httpclient.Options{
+		BaseURL:  os.Getenv("BITBUCKET_BASE_URL"),
+		Username: os.Getenv("BITBUCKET_USERNAME"),
+		Token:    os.Getenv("BITBUCKET_APP_PASSWORD"),
+	}.Apply(opts...)
+	if o.BaseURL == "" {
+		o.BaseURL = defaultBaseURL
+	}
+
+	return &bitbucket{baseURL: o.BaseURL, client: client, username: o.Username, appPassword: o.Token}
+}
+
+// HostFromEnv names the host BITBUCKET_BASE_URL points at: bitbucket.org for
+// the public API.
+func HostFromEnv() string {
+	host := httpclient.Host(os.Getenv("BITBUCKET_BASE_URL"), "bitbucket.org")
+	if host == "api.bitbucket.org" {
+		return "bitbucket.org"
+	}
+	return host
+}
+
+func (b *bitbucket) FetchRepo(ctx context.Context, owner, repo string) (*object.Repository, error) {
+	r, err := b.repository(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	repository := r.toObject()
+	return &repository, nil
+}
+
+// repository reads the 2.0 representation of owner/repo.
+func (b *bitbucket) repository(ctx context.Context, owner, repo string) (*repository, error) {
+	resp, err := b.fetch(ctx, b.repoURL(owner, repo), fmt.Sprintf("repository %s/%s", owner, repo))
+	if err != nil {
+		return nil, err
+	}
+
+	var r repository
+	if err := json.Unmarshal(resp.Body(), &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// FetchCommits hands the history of the main branch to handle, newest first.
+// Bitbucket cannot filter commits by date, so the bounds of opts are applied
+// to the results. Merged history is not in date order, so commits before Since
+// are skipped and the pagination only stops at a page holding nothing newer.
+func (b *bitbucket) FetchCommits(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error {
+	r, err := b.repository(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+	if r.MainBranch == nil || r.MainBranch.Name == "" {
+		// nothing has been committed yet
+		return nil
+	}
+	commitsURL := fmt.Sprintf("%s/commits/%s?pagelen=%d", b.repoURL(owner, repo), url.PathEscape(r.MainBranch.Name), pageLen)
+
+	return b.paginate(ctx, commitsURL, fmt.Sprintf("repository %s/%s", owner, repo), maxCommitPages, func(values json.RawMessage) error {
+		var commits []commit
+		if err := json.Unmarshal(values, &commits); err != nil {
+			return err
+		}
+
+		var (
+			commitList = make([]object.Commit, 0, len(commits))
+			older      int
+		)
+		for _, c := range commits {
+			oc := c.toObject()
+			if !opts.Since.IsZero() && oc.CommittedAt.Before(opts.Since) {
+				older++
+				continue
+			}
+			if !opts.Until.IsZero() && oc.CommittedAt.After(opts.Until) {
+				continue
+			}
+			commitList = append(commitList, oc)
+		}
+		if len(commitList) > 0 {
+			if err := handle(commitList); err != nil {
+				return err
+			}
+		}
+		if older > 0 && older == len(commits) {
+			return httpclient.ErrStop
+		}
+		return nil
+	})
+}
+
+// ListOrgRepos hands every repository of the workspace org to handle.
+func (b *bitbucket) ListOrgRepos(ctx context.Context, org string, handle object.RepoPageFunc) error {
+	return b.listWorkspace(ctx, org, url.Values{}, nil, handle)
+}
+
+// ListUserRepos hands every repository of the personal workspace of user to
+// handle; Bitbucket makes no difference between the two.
+func (b *bitbucket) ListUserRepos(ctx context.Context, user string, handle object.RepoPageFunc) error {
+	return b.listWorkspace(ctx, user, url.Values{}, nil, handle)
+}
+
+// listWorkspace hands the repositories of workspace matching params, and keep
+// when it is not nil, to handle.
+func (b *bitbucket) listWorkspace(ctx context.Context, workspace string, params url.Values, keep func(object.Repository) bool, handle object.RepoPageFunc) error {
+	params.Set("pagelen", strconv.Itoa(pageLen))
+	listURL := fmt.Sprintf("%s/repositories/%s?%s", b.baseURL, url.PathEscape(workspace), params.Encode())
+
+	return b.paginate(ctx, listURL, fmt.Sprintf("workspace %s", workspace), 0, repositoryPages(keep, handle))
+}
+
+// paginate follows the next links of a paginated response from firstURL,
+// handing the values of every page to handle, up to maxPages pages when it is
+// positive, see httpclient.Paginate.
+func (b *bitbucket) paginate(ctx context.Context, firstURL, resource string, maxPages int, handle func(values json.RawMessage) error) error {
+	get := func(ctx context.Context, pageURL string) (*resty.Response, error) {
+		return b.fetch(ctx, pageURL, resource)
+	}
+
+	return httpclient.Paginate(ctx, firstURL, maxPages, get, func(resp *resty.Response) (string, error) {
+		var body struct {
+			Values json.RawMessage `json:"values"`
+			Next   string          `json:"next"`
+		}
+		if err := json.Unmarshal(resp.Body(), &body); err != nil {
+			return "", err
+		}
+		if len(body.Values) > 0 {
+			if err := handle(body.Values); err != nil {
+				return "", err
+			}
+		}
+		return body.Next, nil
+	})
+}
+
+// fetch issues a GET and turns an unsuccessful response for resource into an
+// error. Bitbucket does not say when its hourly quotas reset, so throttling is
+// reported as a SecondaryRateLimitError.
+func (b *bitbucket) fetch(ctx context.Context, url, resource string) (*resty.Response, error) {
+	resp, err := b.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if err := httpclient.CheckResponse(resp, resource, errorMessage); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// get issues a GET, authenticated with the app password when one is configured.
+func (b *bitbucket) get(ctx context.Context, url string) (*resty.Response, error) {
+	req := b.client.R().SetContext(ctx)
+	if b.username != "" {
+		req.SetBasicAuth(b.username, b.appPassword)
+	}
+
+	return req.Get(url)
+}
+
+func (b *bitbucket) repoURL(owner, repo string) string {
+	return fmt.Sprintf("%s/repositories/%s/%s", b.baseURL, url.PathEscape(owner), url.PathEscape(repo))
+}
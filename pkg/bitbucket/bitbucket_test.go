@@ -0,0 +1,107 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/project/pkg/httpclient"
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchRepoMapsWorkspaceToOwner(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repositories/acme/billing", r.URL.Path)
+		user, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "ci-bot", user)
+		assert.Equal(t, "app-password", password)
+
+		_, _ = w.Write([]byte(`{"slug": "billing", "name": "Billing", "language": "go", "workspace": {"slug": "acme"},
+			"links": {"html": {"href": "https://bitbucket.org/acme/billing"}}, "parent": {"full_name": "upstream/billing"},
+			"updated_on": "2024-07-01T10:00:00+00:00"}`))
+	}))
+	defer srv.Close()
+
+	details := NewBitbucket(httpclient.WithBaseURL(srv.URL), WithAppPassword("ci-bot", "app-password"))
+	repo, err := details.FetchRepo(context.Background(), "acme", "billing")
+	require.NoError(t, err)
+
+	assert.Equal(t, "billing", repo.Name)
+	assert.Equal(t, "acme", repo.Owner)
+	assert.Equal(t, "https://bitbucket.org/acme/billing", repo.URL)
+	assert.Equal(t, "2024-07-01T10:00:00Z", repo.UpdatedAt)
+	assert.True(t, repo.Fork)
+}
+
+func TestFetchCommitsFollowsNextUntilSince(t *testing.T) {
+	since := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repositories/acme/billing" {
+			_, _ = w.Write([]byte(`{"slug": "billing", "mainbranch": {"name": "release/2.x"}}`))
+			return
+		}
+		// only the history of the main branch is listed
+		assert.Equal(t, "/repositories/acme/billing/commits/release/2.x", r.URL.Path)
+
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprintf(w, `{"values": [{"hash": "c3", "date": "2024-07-03T00:00:00+00:00", "author": {"raw": "Ada Lovelace <ada@example.com>"}}],
+				"next": "%s/repositories/acme/billing/commits/release%%2F2.x?page=2"}`, srv.URL)
+		case "2":
+			// c1 was merged below c2 and c0, which are newer
+			fmt.Fprintf(w, `{"values": [{"hash": "c2", "date": "2024-07-02T00:00:00+00:00", "author": {"raw": "ci", "user": {"display_name": "CI"}}},
+				{"hash": "c1", "date": "2024-06-30T00:00:00+00:00"}, {"hash": "c0", "date": "2024-07-01T12:00:00+00:00"}],
+				"next": "%s/repositories/acme/billing/commits/release%%2F2.x?page=3"}`, srv.URL)
+		case "3":
+			fmt.Fprintf(w, `{"values": [{"hash": "b2", "date": "2024-06-29T00:00:00+00:00"}, {"hash": "b1", "date": "2024-06-28T00:00:00+00:00"}],
+				"next": "%s/repositories/acme/billing/commits/release%%2F2.x?page=4"}`, srv.URL)
+		default:
+			t.Errorf("requested commits older than since")
+		}
+	}))
+	defer srv.Close()
+
+	var commits []object.Commit
+	err := NewBitbucket(httpclient.WithBaseURL(srv.URL)).FetchCommits(context.Background(), "acme", "billing", object.CommitOptions{Since: since}, func(page []object.Commit) error {
+		commits = append(commits, page...)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Len(t, commits, 3)
+	assert.Equal(t, "Ada Lovelace", commits[0].AuthorName)
+	assert.Equal(t, "ada@example.com", commits[0].AuthorEmail)
+	assert.Equal(t, "CI", commits[1].AuthorName)
+	assert.Equal(t, "c0", commits[2].SHA)
+}
+
+func TestSearchReposSendsBBQL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repositories/acme", r.URL.Path)
+		assert.Equal(t, `(name ~ "pay" OR description ~ "pay") AND language = "go"`, r.URL.Query().Get("q"))
+		assert.Equal(t, "-updated_on", r.URL.Query().Get("sort"))
+
+		_, _ = w.Write([]byte(`{"values": [{"slug": "payments", "workspace": {"slug": "acme"}},
+			{"slug": "payments-fork", "workspace": {"slug": "acme"}, "parent": {}}]}`))
+	}))
+	defer srv.Close()
+
+	query := object.SearchQuery{Keywords: "pay", Language: "Go", Org: "acme", Sort: "updated"}
+	var names []string
+	err := NewBitbucket(httpclient.WithBaseURL(srv.URL)).SearchRepos(context.Background(), query, func(page []object.Repository) error {
+		for _, rr := range page {
+			names = append(names, rr.Name)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"payments"}, names)
+}
@@ -0,0 +1,16 @@
+package bitbucket
+
+import "encoding/json"
+
+// errorMessage extracts error.message of a Bitbucket error body.
+func errorMessage(body []byte) string {
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil {
+		return ""
+	}
+	return e.Error.Message
+}
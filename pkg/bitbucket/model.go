@@ -0,0 +1,88 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"net/mail"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+// repository is the repository representation of the 2.0 API. Bitbucket has
+// no stars, open issue counts or archiving, so those stay zero.
+type repository struct {
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	Language    string `json:"language"`
+	Workspace   struct {
+		Slug string `json:"slug"`
+	} `json:"workspace"`
+	Links struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+	CreatedOn time.Time        `json:"created_on"`
+	UpdatedOn time.Time        `json:"updated_on"`
+	Parent    *json.RawMessage `json:"parent"`
+	// MainBranch is nil for repositories without any commits.
+	MainBranch *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+}
+
+func (r repository) toObject() object.Repository {
+	return object.Repository{
+		Name:        r.Slug,
+		Owner:       r.Workspace.Slug,
+		Description: r.Description,
+		URL:         r.Links.HTML.Href,
+		Language:    r.Language,
+		CreatedAt:   r.CreatedOn.Format(time.RFC3339),
+		UpdatedAt:   r.UpdatedOn.Format(time.RFC3339),
+		Fork:        r.Parent != nil,
+	}
+}
+
+type commit struct {
+	Hash    string    `json:"hash"`
+	Date    time.Time `json:"date"`
+	Message string    `json:"message"`
+	Author  struct {
+		// Raw is the author line of the commit, "Name <email>".
+		Raw  string `json:"raw"`
+		User struct {
+			DisplayName string `json:"display_name"`
+		} `json:"user"`
+	} `json:"author"`
+}
+
+func (c commit) toObject() object.Commit {
+	oc := object.Commit{
+		SHA:        c.Hash,
+		AuthorName: c.Author.User.DisplayName,
+		Message:    c.Message,
+		Date:       c.Date,
+		// Bitbucket only reports a single date per commit
+		CommittedAt: c.Date,
+	}
+	if address, err := mail.ParseAddress(c.Author.Raw); err == nil {
+		oc.AuthorName = address.Name
+		oc.AuthorEmail = address.Address
+	} else if oc.AuthorName == "" {
+		oc.AuthorName = c.Author.Raw
+	}
+	return oc
+}
+
+// repositoryPages decodes pages of repositories, handing those keep accepts, or
+// all of them when keep is nil, to handle.
+func repositoryPages(keep func(object.Repository) bool, handle object.RepoPageFunc) func(values json.RawMessage) error {
+	return func(values json.RawMessage) error {
+		var repos []repository
+		if err := json.Unmarshal(values, &repos); err != nil {
+			return err
+		}
+		return object.HandleRepos(repos, repository.toObject, keep, handle)
+	}
+}
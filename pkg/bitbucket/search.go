@@ -0,0 +1,92 @@
+package bitbucket
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+// ValidateSearchQuery reports whether Bitbucket can serve query. Repositories
+// can only be searched within a workspace, given as the org qualifier, and
+// have no stars or topics to filter by; only updated is a supported sort.
+func (b *bitbucket) ValidateSearchQuery(q object.SearchQuery) error {
+	if q.Org == "" {
+		return fmt.Errorf("bitbucket searches need an org qualifier naming the workspace")
+	}
+	if q.StarsAbove != 0 {
+		return fmt.Errorf("bitbucket repositories have no stars to filter by")
+	}
+	if len(q.Topics) > 0 {
+		return fmt.Errorf("bitbucket repositories have no topics to filter by")
+	}
+	if q.Archived != nil && *q.Archived {
+		return fmt.Errorf("bitbucket repositories cannot be archived")
+	}
+	switch q.Fork {
+	case object.ForksExcluded, object.ForksIncluded, object.ForksOnly:
+	default:
+		return fmt.Errorf("invalid search fork filter %q", q.Fork)
+	}
+	if q.Sort != "" && q.Sort != "updated" {
+		return fmt.Errorf("bitbucket cannot sort repositories by %q", q.Sort)
+	}
+	if q.Order != "" && q.Order != "asc" && q.Order != "desc" {
+		return fmt.Errorf("invalid search order %q", q.Order)
+	}
+
+	return nil
+}
+
+// SearchRepos hands every repository of the workspace matching query to handle.
+// The qualifiers are sent as a BBQL filter; forks are filtered on the results.
+func (b *bitbucket) SearchRepos(ctx context.Context, q object.SearchQuery, handle object.RepoPageFunc) error {
+	if err := b.ValidateSearchQuery(q); err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	if filter := bbql(q); filter != "" {
+		params.Set("q", filter)
+	}
+	if q.Sort != "" {
+		sort := "-updated_on"
+		if q.Order == "asc" {
+			sort = "updated_on"
+		}
+		params.Set("sort", sort)
+	}
+
+	keep := func(rr object.Repository) bool {
+		switch q.Fork {
+		case object.ForksExcluded:
+			return !rr.Fork
+		case object.ForksOnly:
+			return rr.Fork
+		}
+		return true
+	}
+
+	return b.listWorkspace(ctx, q.Org, params, keep, handle)
+}
+
+// bbql renders the keywords, language and last push of q as a BBQL filter.
+func bbql(q object.SearchQuery) string {
+	var terms []string
+	if keywords := strings.TrimSpace(q.Keywords); keywords != "" {
+		terms = append(terms, fmt.Sprintf("(name ~ %s OR description ~ %s)", strconv.Quote(keywords), strconv.Quote(keywords)))
+	}
+	if q.Language != "" {
+		// Bitbucket stores languages in lower case
+		terms = append(terms, fmt.Sprintf("language = %s", strconv.Quote(strings.ToLower(q.Language))))
+	}
+	if !q.PushedAfter.IsZero() {
+		terms = append(terms, fmt.Sprintf("updated_on > %s", q.PushedAfter.UTC().Format(time.RFC3339)))
+	}
+
+	return strings.Join(terms, " AND ")
+}
@@ -0,0 +1,14 @@
+package gitea
+
+import "encoding/json"
+
+// errorMessage extracts the message field of a Gitea error body.
+func errorMessage(body []byte) string {
+	var e struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil {
+		return ""
+	}
+	return e.Message
+}
@@ -0,0 +1,198 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/httpclient"
+	"github.com/project/pkg/object"
+)
+
+const (
+	// perPage is the default MAX_RESPONSE_ITEMS of a Gitea instance.
+	perPage = 50
+	// maxCommitPages bounds a single FetchCommits call to 5,000 commits.
+	maxCommitPages = 100
+
+	totalCountHeader = "X-Total-Count"
+)
+
+type gitea struct {
+	baseURL string
+	client  *resty.Client
+	token   string
+}
+
+// NewGitea returns a GitDetails reading repositories from a Gitea or Forgejo
+// instance, which share the v1 API. The API at GITEA_BASE_URL, e.g.
+// https://gitea.example.com/api/v1, is read with the access token in
+// GITEA_TOKEN unless opts say otherwise.
+func NewGitea(opts ...httpclient.Option) object.GitDetails {
+	o, client := httpclient.Options{
+		BaseURL: os.Getenv("GITEA_BASE_URL"),
+		Token:   os.Getenv("GITEA_TOKEN"),
+	}.Apply(opts...)
+
+	return &gitea{baseURL: o.BaseURL, client: client, token: o.Token}
+}
+
+// HostFromEnv names the host GITEA_BASE_URL points at, empty when it is unset.
+func HostFromEnv() string {
+	return httpclient.Host(os.Getenv("GITEA_BASE_URL"), "")
+}
+
+func (g *gitea) FetchRepo(ctx context.Context, owner, repo string) (*object.Repository, error) {
+	repoURL := fmt.Sprintf("%s/repos/%s/%s", g.baseURL, url.PathEscape(owner), url.PathEscape(repo))
+
+	resp, err := g.fetch(ctx, repoURL, fmt.Sprintf("repository %s/%s", owner, repo))
+	if err != nil {
+		return nil, err
+	}
+
+	var r repository
+	if err := json.Unmarshal(resp.Body(), &r); err != nil {
+		return nil, err
+	}
+
+	repository := r.toObject()
+	return &repository, nil
+}
+
+// FetchCommits hands the history of the default branch to handle. Instances
+// older than the since and until parameters ignore them, so the bounds are
+// applied to the results as well. Merged history is not in date order, so the
+// listing is read to the end rather than stopped at the first older commit.
+func (g *gitea) FetchCommits(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(perPage))
+	// the per-commit diff stats and signature checks are expensive and unused
+	query.Set("stat", "false")
+	query.Set("verification", "false")
+	query.Set("files", "false")
+	if !opts.Since.IsZero() {
+		query.Set("since", opts.Since.UTC().Format(time.RFC3339))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", opts.Until.UTC().Format(time.RFC3339))
+	}
+	commitsURL := fmt.Sprintf("%s/repos/%s/%s/commits?%s", g.baseURL, url.PathEscape(owner), url.PathEscape(repo), query.Encode())
+
+	return g.paginate(ctx, commitsURL, fmt.Sprintf("repository %s/%s", owner, repo), maxCommitPages, func(body []byte) (int, error) {
+		var commits []commit
+		if err := json.Unmarshal(body, &commits); err != nil {
+			return 0, err
+		}
+
+		commitList := make([]object.Commit, 0, len(commits))
+		for _, c := range commits {
+			oc := c.toObject()
+			if !opts.Since.IsZero() && oc.CommittedAt.Before(opts.Since) {
+				continue
+			}
+			if !opts.Until.IsZero() && oc.CommittedAt.After(opts.Until) {
+				continue
+			}
+			commitList = append(commitList, oc)
+		}
+		if len(commitList) > 0 {
+			if err := handle(commitList); err != nil {
+				return 0, err
+			}
+		}
+		return len(commits), nil
+	})
+}
+
+// ListOrgRepos hands every repository of org to handle.
+func (g *gitea) ListOrgRepos(ctx context.Context, org string, handle object.RepoPageFunc) error {
+	listURL := fmt.Sprintf("%s/orgs/%s/repos?limit=%d", g.baseURL, url.PathEscape(org), perPage)
+
+	return g.paginate(ctx, listURL, fmt.Sprintf("organization %s", org), 0, repositoryPages(nil, handle))
+}
+
+// ListUserRepos hands every repository owned by user to handle.
+func (g *gitea) ListUserRepos(ctx context.Context, user string, handle object.RepoPageFunc) error {
+	listURL := fmt.Sprintf("%s/users/%s/repos?limit=%d", g.baseURL, url.PathEscape(user), perPage)
+
+	return g.paginate(ctx, listURL, fmt.Sprintf("user %s", user), 0, repositoryPages(nil, handle))
+}
+
+// paginate requests the pages of firstURL in turn, handing every page body to
+// handle, up to maxPages pages when it is positive, see httpclient.Paginate.
+// handle returns the number of items the page held; the listing ends once
+// X-Total-Count items were seen, or at a short page when the instance does not
+// report a total.
+func (g *gitea) paginate(ctx context.Context, firstURL, resource string, maxPages int, handle func(body []byte) (int, error)) error {
+	pageURL, err := withPage(firstURL, 1)
+	if err != nil {
+		return err
+	}
+	get := func(ctx context.Context, pageURL string) (*resty.Response, error) {
+		return g.fetch(ctx, pageURL, resource)
+	}
+
+	page, seen := 1, 0
+	return httpclient.Paginate(ctx, pageURL, maxPages, get, func(resp *resty.Response) (string, error) {
+		n, err := handle(resp.Body())
+		if err != nil {
+			return "", err
+		}
+		seen += n
+
+		if n == 0 {
+			return "", nil
+		}
+		if total, err := strconv.Atoi(resp.Header().Get(totalCountHeader)); err == nil {
+			if seen >= total {
+				return "", nil
+			}
+		} else if n < perPage {
+			return "", nil
+		}
+
+		page++
+		return withPage(firstURL, page)
+	})
+}
+
+// fetch issues a GET and turns an unsuccessful response for resource into an
+// error. Gitea has no API quota of its own; throttling by a proxy in front of it
+// is reported as a SecondaryRateLimitError.
+func (g *gitea) fetch(ctx context.Context, url, resource string) (*resty.Response, error) {
+	resp, err := g.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if err := httpclient.CheckResponse(resp, resource, errorMessage); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// get issues a GET, authenticated when a token is configured.
+func (g *gitea) get(ctx context.Context, url string) (*resty.Response, error) {
+	req := g.client.R().SetContext(ctx).SetHeader("Accept", "application/json")
+	if g.token != "" {
+		req.SetHeader("Authorization", "token "+g.token)
+	}
+
+	return req.Get(url)
+}
+
+// withPage sets the page parameter of rawURL.
+func withPage(rawURL string, page int) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
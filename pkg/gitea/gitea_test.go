@@ -0,0 +1,102 @@
+package gitea
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/project/pkg/httpclient"
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchRepo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/infra/deploy", r.URL.Path)
+		assert.Equal(t, "token secret", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"name": "deploy", "owner": {"login": "infra"}, "language": "Go", "stars_count": 3,
+			"created_at": "2023-01-02T03:04:05Z", "updated_at": "2024-07-01T10:00:00Z", "archived": true}`))
+	}))
+	defer srv.Close()
+
+	repo, err := NewGitea(httpclient.WithBaseURL(srv.URL), httpclient.WithToken("secret")).FetchRepo(context.Background(), "infra", "deploy")
+	require.NoError(t, err)
+
+	assert.Equal(t, "infra", repo.Owner)
+	assert.Equal(t, "Go", repo.Language)
+	assert.Equal(t, 3, repo.StarsCount)
+	assert.Equal(t, "2024-07-01T10:00:00Z", repo.UpdatedAt)
+	assert.True(t, repo.Archived)
+}
+
+func TestFetchCommitsFiltersBySince(t *testing.T) {
+	since := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	var pages []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/infra/deploy/commits", r.URL.Path)
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+
+		// an instance that ignores since returns older commits too; c2 was
+		// authored before since but rebased after it, and c0 was merged below c1
+		w.Header().Set(totalCountHeader, "4")
+		if page == "1" {
+			_, _ = w.Write([]byte(`[{"sha": "c3", "commit": {"author": {"name": "ada", "date": "2024-07-03T00:00:00Z"}, "committer": {"date": "2024-07-03T00:00:00Z"}}}]`))
+			return
+		}
+		if page == "2" {
+			_, _ = w.Write([]byte(`[{"sha": "c2", "commit": {"author": {"date": "2024-06-20T00:00:00Z"}, "committer": {"date": "2024-07-02T00:00:00Z"}}},
+				{"sha": "c1", "commit": {"author": {"date": "2024-06-30T00:00:00Z"}, "committer": {"date": "2024-06-30T00:00:00Z"}}}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"sha": "c0", "commit": {"author": {"date": "2024-07-01T12:00:00Z"}, "committer": {"date": "2024-07-01T12:00:00Z"}}}]`))
+	}))
+	defer srv.Close()
+
+	var shas []string
+	err := NewGitea(httpclient.WithBaseURL(srv.URL)).FetchCommits(context.Background(), "infra", "deploy", object.CommitOptions{Since: since}, func(page []object.Commit) error {
+		for _, c := range page {
+			shas = append(shas, c.SHA)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"c3", "c2", "c0"}, shas)
+	assert.Equal(t, []string{"1", "2", "3"}, pages)
+}
+
+func TestSearchReposFiltersResults(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/infra":
+			_, _ = w.Write([]byte(`{"id": 42, "login": "infra"}`))
+		case "/repos/search":
+			assert.Equal(t, "deploy", r.URL.Query().Get("q"))
+			assert.Equal(t, "42", r.URL.Query().Get("uid"))
+			assert.Equal(t, "stars", r.URL.Query().Get("sort"))
+			_, _ = w.Write([]byte(`{"ok": true, "data": [
+				{"name": "deploy", "owner": {"login": "infra"}, "language": "Go", "stars_count": 20},
+				{"name": "deploy-fork", "owner": {"login": "infra"}, "language": "Go", "stars_count": 30, "fork": true},
+				{"name": "deploy-docs", "owner": {"login": "infra"}, "language": "Markdown", "stars_count": 40}]}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	query := object.SearchQuery{Keywords: "deploy", Org: "infra", Language: "go", StarsAbove: 10, Sort: "stars"}
+	var names []string
+	err := NewGitea(httpclient.WithBaseURL(srv.URL)).SearchRepos(context.Background(), query, func(page []object.Repository) error {
+		for _, rr := range page {
+			names = append(names, rr.Name)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"deploy"}, names)
+}
@@ -0,0 +1,84 @@
+package gitea
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+// repository is the repository representation of the v1 API.
+type repository struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	Description     string    `json:"description"`
+	HTMLURL         string    `json:"html_url"`
+	Language        string    `json:"language"`
+	ForksCount      int       `json:"forks_count"`
+	StarsCount      int       `json:"stars_count"`
+	OpenIssuesCount int       `json:"open_issues_count"`
+	WatchersCount   int       `json:"watchers_count"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	Fork            bool      `json:"fork"`
+	Archived        bool      `json:"archived"`
+}
+
+func (r repository) toObject() object.Repository {
+	return object.Repository{
+		Name:            r.Name,
+		Owner:           r.Owner.Login,
+		Description:     r.Description,
+		URL:             r.HTMLURL,
+		Language:        r.Language,
+		ForksCount:      r.ForksCount,
+		StarsCount:      r.StarsCount,
+		OpenIssuesCount: r.OpenIssuesCount,
+		WatchersCount:   r.WatchersCount,
+		CreatedAt:       r.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       r.UpdatedAt.Format(time.RFC3339),
+		Fork:            r.Fork,
+		Archived:        r.Archived,
+	}
+}
+
+type commit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Author struct {
+			Name  string    `json:"name"`
+			Email string    `json:"email"`
+			Date  time.Time `json:"date"`
+		} `json:"author"`
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+		Message string `json:"message"`
+	} `json:"commit"`
+}
+
+func (c commit) toObject() object.Commit {
+	return object.Commit{
+		SHA:         c.SHA,
+		AuthorName:  c.Commit.Author.Name,
+		AuthorEmail: c.Commit.Author.Email,
+		Message:     c.Commit.Message,
+		Date:        c.Commit.Author.Date,
+		CommittedAt: c.Commit.Committer.Date,
+	}
+}
+
+// repositoryPages decodes pages of repositories, handing those keep accepts, or
+// all of them when keep is nil, to handle.
+func repositoryPages(keep func(object.Repository) bool, handle object.RepoPageFunc) func(body []byte) (int, error) {
+	return func(body []byte) (int, error) {
+		var repos []repository
+		if err := json.Unmarshal(body, &repos); err != nil {
+			return 0, err
+		}
+
+		return len(repos), object.HandleRepos(repos, repository.toObject, keep, handle)
+	}
+}
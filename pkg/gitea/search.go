@@ -0,0 +1,136 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+// searchSorts are the sorts of object.SearchQuery the search API supports,
+// under the same names.
+var searchSorts = map[string]bool{
+	"stars":   true,
+	"forks":   true,
+	"updated": true,
+}
+
+// ValidateSearchQuery reports whether the search API can serve query. Gitea
+// matches a single topic exactly and cannot combine it with keywords, and has
+// no help-wanted issues to sort by.
+func (g *gitea) ValidateSearchQuery(q object.SearchQuery) error {
+	keywords := strings.TrimSpace(q.Keywords)
+	if keywords == "" && len(q.Topics) == 0 && q.Org == "" {
+		return fmt.Errorf("search query needs keywords or a topic or org qualifier")
+	}
+	if len(q.Topics) > 1 || (len(q.Topics) == 1 && keywords != "") {
+		return fmt.Errorf("gitea searches a single topic without keywords")
+	}
+	if q.StarsAbove < 0 {
+		return fmt.Errorf("invalid search star count %d", q.StarsAbove)
+	}
+	switch q.Fork {
+	case object.ForksExcluded, object.ForksIncluded, object.ForksOnly:
+	default:
+		return fmt.Errorf("invalid search fork filter %q", q.Fork)
+	}
+	if q.Sort != "" && !searchSorts[q.Sort] {
+		return fmt.Errorf("gitea cannot sort repositories by %q", q.Sort)
+	}
+	if q.Order != "" && q.Order != "asc" && q.Order != "desc" {
+		return fmt.Errorf("invalid search order %q", q.Order)
+	}
+
+	return nil
+}
+
+// SearchRepos hands every repository matching query to handle. Qualifiers the
+// search API has no parameter for, language, stars, last push and excluding
+// forks, are applied to the results.
+func (g *gitea) SearchRepos(ctx context.Context, q object.SearchQuery, handle object.RepoPageFunc) error {
+	if err := g.ValidateSearchQuery(q); err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(perPage))
+	if keywords := strings.TrimSpace(q.Keywords); keywords != "" {
+		params.Set("q", keywords)
+	}
+	if len(q.Topics) == 1 {
+		params.Set("q", q.Topics[0])
+		params.Set("topic", "true")
+	}
+	if q.Org != "" {
+		uid, err := g.ownerID(ctx, q.Org)
+		if err != nil {
+			return err
+		}
+		params.Set("uid", strconv.FormatInt(uid, 10))
+		params.Set("exclusive", "true")
+	}
+	if q.Archived != nil {
+		params.Set("archived", strconv.FormatBool(*q.Archived))
+	}
+	if q.Fork == object.ForksOnly {
+		params.Set("mode", "fork")
+	}
+	if q.Sort != "" {
+		params.Set("sort", q.Sort)
+		order := q.Order
+		if order == "" {
+			order = "desc"
+		}
+		params.Set("order", order)
+	}
+	searchURL := fmt.Sprintf("%s/repos/search?%s", g.baseURL, params.Encode())
+
+	keep := func(rr object.Repository) bool {
+		if q.Language != "" && !strings.EqualFold(rr.Language, q.Language) {
+			return false
+		}
+		if q.StarsAbove > 0 && rr.StarsCount <= q.StarsAbove {
+			return false
+		}
+		if !q.PushedAfter.IsZero() {
+			updated, err := time.Parse(time.RFC3339, rr.UpdatedAt)
+			if err == nil && !updated.After(q.PushedAfter) {
+				return false
+			}
+		}
+		return q.Fork != object.ForksExcluded || !rr.Fork
+	}
+
+	return g.paginate(ctx, searchURL, "repositories", 0, func(body []byte) (int, error) {
+		var result struct {
+			Data []repository `json:"data"`
+		}
+		if err := json.Unmarshal(body, &result); err != nil {
+			return 0, err
+		}
+
+		return len(result.Data), object.HandleRepos(result.Data, repository.toObject, keep, handle)
+	})
+}
+
+// ownerID looks up the numeric id of the user or organization login, which the
+// search API filters owners by.
+func (g *gitea) ownerID(ctx context.Context, login string) (int64, error) {
+	resp, err := g.fetch(ctx, fmt.Sprintf("%s/users/%s", g.baseURL, url.PathEscape(login)), fmt.Sprintf("owner %s", login))
+	if err != nil {
+		return 0, err
+	}
+
+	var owner struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(resp.Body(), &owner); err != nil {
+		return 0, err
+	}
+	return owner.ID, nil
+}
@@ -0,0 +1,367 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/object"
+)
+
+const (
+	// appJWTLifetime stays under the ten minutes GitHub accepts for app JWTs.
+	appJWTLifetime = 9 * time.Minute
+	// appClockSkew backdates the JWT issue time to tolerate clock drift.
+	appClockSkew = time.Minute
+	// installationTokenRefreshMargin renews installation tokens this long before they expire.
+	installationTokenRefreshMargin = 5 * time.Minute
+)
+
+// AppConfig describes a GitHub App the client authenticates as.
+type AppConfig struct {
+	AppID      int64
+	PrivateKey *rsa.PrivateKey
+	// Installations maps an owner to the installation covering it. Owners that
+	// are missing are looked up through the API on first use.
+	Installations map[string]int64
+	// DefaultInstallation serves requests that are not tied to an owner, such as
+	// search. When it is zero the first installation of the app is used.
+	DefaultInstallation int64
+	// BaseURL overrides the base URL of the client for the app endpoints.
+	BaseURL string
+}
+
+// AppConfigFromEnv reads the GitHub App settings:
+//
+//	GITHUB_APP_ID                  numeric app id; the app is disabled when empty
+//	GITHUB_APP_PRIVATE_KEY_PATH    PEM encoded private key of the app
+//	GITHUB_APP_INSTALLATIONS       optional owner=installation_id pairs, comma separated
+//	GITHUB_APP_INSTALLATION_ID     optional installation used for search, the
+//	                               first installation of the app when empty
+//
+// It returns nil when no app is configured.
+func AppConfigFromEnv() (*AppConfig, error) {
+	return appConfigFromEnv("GITHUB_")
+}
+
+// appConfigFromEnv reads the app settings of AppConfigFromEnv from variables
+// named with prefix instead of GITHUB_.
+func appConfigFromEnv(prefix string) (*AppConfig, error) {
+	appID := os.Getenv(prefix + "APP_ID")
+	if appID == "" {
+		return nil, nil
+	}
+
+	cfg := AppConfig{Installations: make(map[string]int64)}
+
+	var err error
+	if cfg.AppID, err = strconv.ParseInt(appID, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid %sAPP_ID: %w", prefix, err)
+	}
+
+	keyPEM, err := os.ReadFile(os.Getenv(prefix + "APP_PRIVATE_KEY_PATH"))
+	if err != nil {
+		return nil, fmt.Errorf("reading %sAPP_PRIVATE_KEY_PATH: %w", prefix, err)
+	}
+	if cfg.PrivateKey, err = ParsePrivateKey(keyPEM); err != nil {
+		return nil, err
+	}
+
+	for _, pair := range strings.Split(os.Getenv(prefix+"APP_INSTALLATIONS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		owner, id, found := strings.Cut(pair, "=")
+		installation, err := strconv.ParseInt(id, 10, 64)
+		if !found || err != nil {
+			return nil, fmt.Errorf("invalid %sAPP_INSTALLATIONS entry %q", prefix, pair)
+		}
+		cfg.Installations[strings.ToLower(owner)] = installation
+	}
+
+	if id := os.Getenv(prefix + "APP_INSTALLATION_ID"); id != "" {
+		if cfg.DefaultInstallation, err = strconv.ParseInt(id, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid %sAPP_INSTALLATION_ID: %w", prefix, err)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// ParsePrivateKey decodes the PEM private key GitHub issues for an app, in
+// either PKCS#1 or PKCS#8 form.
+func ParsePrivateKey(keyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("github app private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing github app private key: %w", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("github app private key is not an RSA key")
+	}
+	return key, nil
+}
+
+type installationToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// appAuth authenticates as a GitHub App, exchanging signed JWTs for installation
+// access tokens and refreshing them before they expire.
+type appAuth struct {
+	cfg    AppConfig
+	client *resty.Client
+	now    func() time.Time
+
+	mu            sync.Mutex
+	installations map[string]int64
+	tokens        map[int64]installationToken
+	// refreshing serializes the renewal of each installation's token, so that
+	// concurrent requests wait for a single new token instead of each minting one.
+	refreshing map[int64]*sync.Mutex
+}
+
+func newAppAuth(cfg AppConfig, client *resty.Client) *appAuth {
+	installations := make(map[string]int64, len(cfg.Installations))
+	for owner, id := range cfg.Installations {
+		installations[strings.ToLower(owner)] = id
+	}
+
+	return &appAuth{
+		cfg:           cfg,
+		client:        client,
+		now:           time.Now,
+		installations: installations,
+		tokens:        make(map[int64]installationToken),
+		refreshing:    make(map[int64]*sync.Mutex),
+	}
+}
+
+// credential authenticates as the installation covering owner. An installation
+// has a single token for every resource, so there is nothing to rotate to.
+func (a *appAuth) credential(ctx context.Context, owner, _ string, _ map[string]bool) (credential, error) {
+	installation, err := a.installation(ctx, owner)
+	if err != nil {
+		return credential{}, err
+	}
+
+	token, err := a.installationToken(ctx, installation)
+	if err != nil {
+		return credential{}, err
+	}
+
+	return credential{
+		id:            fmt.Sprintf("installation:%d", installation),
+		authorization: "token " + token,
+	}, nil
+}
+
+// installation resolves the installation covering owner, asking GitHub when it
+// is not configured; owners are looked up as organizations first, then as
+// users. Requests without an owner use the default installation, or the first
+// installation of the app when none is configured.
+func (a *appAuth) installation(ctx context.Context, owner string) (int64, error) {
+	owner = strings.ToLower(owner)
+
+	a.mu.Lock()
+	id, ok := a.installations[owner]
+	a.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	if owner == "" && a.cfg.DefaultInstallation != 0 {
+		return a.cfg.DefaultInstallation, nil
+	}
+
+	jwt, err := a.jwt()
+	if err != nil {
+		return 0, err
+	}
+
+	if owner == "" {
+		id, err = a.firstInstallation(ctx, jwt)
+	} else {
+		id, err = a.lookupInstallation(ctx, jwt, fmt.Sprintf("%s/orgs/%s/installation", a.cfg.BaseURL, owner), owner)
+		var notFound *object.NotFoundError
+		if errors.As(err, &notFound) {
+			id, err = a.lookupInstallation(ctx, jwt, fmt.Sprintf("%s/users/%s/installation", a.cfg.BaseURL, owner), owner)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	a.mu.Lock()
+	a.installations[owner] = id
+	a.mu.Unlock()
+
+	return id, nil
+}
+
+// lookupInstallation reads the id of the installation at installationURL.
+func (a *appAuth) lookupInstallation(ctx context.Context, jwt, installationURL, owner string) (int64, error) {
+	var body struct {
+		ID int64 `json:"id"`
+	}
+	resp, err := a.client.R().
+		SetContext(ctx).
+		SetHeader("Authorization", "Bearer "+jwt).
+		Get(installationURL)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkResponse(resp, fmt.Sprintf("installation for %s", owner)); err != nil {
+		return 0, fmt.Errorf("github app: %w", err)
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return 0, err
+	}
+
+	return body.ID, nil
+}
+
+// firstInstallation reads the id of the first installation of the app, for
+// requests without an owner when no default installation is configured.
+func (a *appAuth) firstInstallation(ctx context.Context, jwt string) (int64, error) {
+	var body []struct {
+		ID int64 `json:"id"`
+	}
+	resp, err := a.client.R().
+		SetContext(ctx).
+		SetHeader("Authorization", "Bearer "+jwt).
+		SetQueryParam("per_page", "1").
+		Get(a.cfg.BaseURL + "/app/installations")
+	if err != nil {
+		return 0, err
+	}
+	if err := checkResponse(resp, "installations of the app"); err != nil {
+		return 0, fmt.Errorf("github app: %w", err)
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return 0, err
+	}
+	if len(body) == 0 {
+		return 0, fmt.Errorf("github app: %w", &object.NotFoundError{Resource: "installation of the app"})
+	}
+
+	return body[0].ID, nil
+}
+
+// installationToken returns a cached access token for installation, exchanging a
+// fresh JWT for a new one when it is missing or about to expire. Concurrent
+// callers share a single renewal.
+func (a *appAuth) installationToken(ctx context.Context, installation int64) (string, error) {
+	if token, ok := a.cachedToken(installation); ok {
+		return token, nil
+	}
+
+	a.mu.Lock()
+	refresh, ok := a.refreshing[installation]
+	if !ok {
+		refresh = &sync.Mutex{}
+		a.refreshing[installation] = refresh
+	}
+	a.mu.Unlock()
+
+	refresh.Lock()
+	defer refresh.Unlock()
+	// another caller may have renewed the token while this one waited
+	if token, ok := a.cachedToken(installation); ok {
+		return token, nil
+	}
+
+	jwt, err := a.jwt()
+	if err != nil {
+		return "", err
+	}
+
+	var body struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	resp, err := a.client.R().
+		SetContext(ctx).
+		SetHeader("Authorization", "Bearer "+jwt).
+		SetHeader("Accept", "application/vnd.github+json").
+		Post(fmt.Sprintf("%s/app/installations/%d/access_tokens", a.cfg.BaseURL, installation))
+	if err != nil {
+		return "", err
+	}
+	if err := checkResponse(resp, fmt.Sprintf("installation %d", installation)); err != nil {
+		return "", fmt.Errorf("github app: creating installation token: %w", err)
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return "", err
+	}
+
+	a.mu.Lock()
+	a.tokens[installation] = installationToken{token: body.Token, expiresAt: body.ExpiresAt}
+	a.mu.Unlock()
+
+	return body.Token, nil
+}
+
+// cachedToken returns the token of installation while it is not about to expire.
+func (a *appAuth) cachedToken(installation int64) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cached, ok := a.tokens[installation]
+	if !ok || !a.now().Add(installationTokenRefreshMargin).Before(cached.expiresAt) {
+		return "", false
+	}
+	return cached.token, true
+}
+
+// jwt signs the short-lived RS256 token that identifies the app itself.
+func (a *appAuth) jwt() (string, error) {
+	now := a.now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-appClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": a.cfg.AppID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.cfg.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,159 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTokenEndpoint verifies the app JWT and issues installation tokens that
+// expire after ttl.
+func fakeTokenEndpoint(t *testing.T, key *rsa.PrivateKey, ttl time.Duration) (*httptest.Server, *int32) {
+	var issued int32
+	mux := http.NewServeMux()
+	verify := func(r *http.Request) bool {
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		if len(parts) != 3 {
+			return false
+		}
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return false
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		return rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature) == nil
+	}
+
+	installation := func(w http.ResponseWriter, r *http.Request) {
+		if !verify(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]int64{"id": 42})
+	}
+	mux.HandleFunc("GET /users/octo/installation", installation)
+	mux.HandleFunc("GET /orgs/acme/installation", installation)
+	mux.HandleFunc("GET /app/installations", func(w http.ResponseWriter, r *http.Request) {
+		if !verify(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]map[string]int64{{"id": 42}})
+	})
+	mux.HandleFunc("POST /app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		if !verify(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(&issued, 1)
+		// slow enough for concurrent callers to pile up behind a renewal
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"token":      "ghs_" + string(rune('0'+n)),
+			"expires_at": time.Now().Add(ttl),
+		})
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &issued
+}
+
+func TestAppAuthInstallationToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	srv, issued := fakeTokenEndpoint(t, key, time.Hour)
+	auth := newAppAuth(AppConfig{AppID: 1, PrivateKey: key, BaseURL: srv.URL}, resty.New())
+
+	cred, err := auth.credential(context.Background(), "octo", object.ResourceCore, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "installation:42", cred.id)
+	assert.Equal(t, "token ghs_1", cred.authorization)
+
+	// the token is reused while it is valid
+	_, err = auth.credential(context.Background(), "Octo", object.ResourceCore, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(issued))
+
+	// and refreshed once it is about to expire
+	auth.now = func() time.Time { return time.Now().Add(58 * time.Minute) }
+	cred, err = auth.credential(context.Background(), "octo", object.ResourceCore, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "token ghs_2", cred.authorization)
+}
+
+func TestAppAuthUnknownOwner(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	srv, _ := fakeTokenEndpoint(t, key, time.Hour)
+	auth := newAppAuth(AppConfig{AppID: 1, PrivateKey: key, BaseURL: srv.URL}, resty.New())
+
+	_, err = auth.credential(context.Background(), "stranger", object.ResourceCore, nil)
+	assert.Error(t, err)
+}
+
+func TestAppAuthFirstInstallationWithoutDefault(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	srv, _ := fakeTokenEndpoint(t, key, time.Hour)
+	auth := newAppAuth(AppConfig{AppID: 1, PrivateKey: key, BaseURL: srv.URL}, resty.New())
+
+	// search is not tied to an owner
+	cred, err := auth.credential(context.Background(), "", object.ResourceSearch, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "installation:42", cred.id)
+}
+
+func TestAppAuthOrganizationInstallation(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	srv, _ := fakeTokenEndpoint(t, key, time.Hour)
+	auth := newAppAuth(AppConfig{AppID: 1, PrivateKey: key, BaseURL: srv.URL}, resty.New())
+
+	cred, err := auth.credential(context.Background(), "acme", object.ResourceCore, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "installation:42", cred.id)
+}
+
+func TestAppAuthRenewsTokenOnce(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	srv, issued := fakeTokenEndpoint(t, key, time.Hour)
+	auth := newAppAuth(AppConfig{AppID: 1, PrivateKey: key, BaseURL: srv.URL, Installations: map[string]int64{"octo": 42}}, resty.New())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cred, err := auth.credential(context.Background(), "octo", object.ResourceCore, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, "token ghs_1", cred.authorization)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(issued))
+}
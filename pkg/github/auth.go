@@ -0,0 +1,94 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// anonymous identifies unauthenticated requests in the quota tracker.
+const anonymous = "anonymous"
+
+// credential is what a request authenticates with. id names it for quota
+// tracking without exposing the secret.
+type credential struct {
+	id            string
+	authorization string
+}
+
+// authenticator picks the credential used for a request against the rate-limit
+// resource concerning owner, passing over the credentials in tried when it has
+// others to offer.
+type authenticator interface {
+	credential(ctx context.Context, owner, resource string, tried map[string]bool) (credential, error)
+}
+
+// tokenPool rotates through personal access tokens, always handing out the one
+// with the most remaining quota for the resource requested.
+type tokenPool struct {
+	tokens []credential
+	quota  *quotaTracker
+}
+
+func newTokenPool(tokens []string, quota *quotaTracker) *tokenPool {
+	pool := &tokenPool{quota: quota}
+	for _, token := range tokens {
+		pool.tokens = append(pool.tokens, credential{
+			id:            maskToken(token),
+			authorization: "Bearer " + token,
+		})
+	}
+
+	return pool
+}
+
+// credential hands out the untried token with the most quota left for
+// resource, or a tried one once every token has been tried.
+func (p *tokenPool) credential(_ context.Context, _, resource string, tried map[string]bool) (credential, error) {
+	if len(p.tokens) == 0 {
+		return credential{id: anonymous}, nil
+	}
+
+	best := p.tokens[0]
+	bestRemaining := -1
+	for _, token := range p.tokens {
+		if tried[token.id] {
+			continue
+		}
+		remaining, known := p.quota.remaining(token.id, resource)
+		if !known {
+			// a fresh or reset token is as good as it gets
+			return token, nil
+		}
+		if remaining > bestRemaining {
+			best, bestRemaining = token, remaining
+		}
+	}
+
+	return best, nil
+}
+
+// tokensFromEnv reads GITHUB_TOKEN and the comma separated GITHUB_TOKENS pool.
+func tokensFromEnv() []string {
+	var tokens []string
+	if token := strings.TrimSpace(os.Getenv("GITHUB_TOKEN")); token != "" {
+		tokens = append(tokens, token)
+	}
+
+	for _, token := range strings.Split(os.Getenv("GITHUB_TOKENS"), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+// maskToken keeps only the last four characters of a token.
+func maskToken(token string) string {
+	if len(token) <= 4 {
+		return "token:****"
+	}
+	return fmt.Sprintf("token:****%s", token[len(token)-4:])
+}
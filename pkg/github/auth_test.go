@@ -0,0 +1,86 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenPoolPicksMostRemaining(t *testing.T) {
+	quota := newQuotaTracker()
+	pool := newTokenPool([]string{"ghp_first", "ghp_second"}, quota)
+
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	observe := func(token string, remaining int) {
+		header := http.Header{}
+		header.Set(rateLimitingRemainingHeader, strconv.Itoa(remaining))
+		header.Set(rateLimitingResetHeader, reset)
+		quota.observe(maskToken(token), header)
+	}
+
+	observe("ghp_first", 10)
+	cred, err := pool.credential(context.Background(), "", object.ResourceCore, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer ghp_second", cred.authorization, "unused token should be preferred")
+
+	observe("ghp_second", 3)
+	cred, err = pool.credential(context.Background(), "", object.ResourceCore, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer ghp_first", cred.authorization)
+	assert.Len(t, quota.snapshot(), 2)
+}
+
+// Test a token out of search quota is passed over for search even though it has the most core quota left
+func TestSendRotatesTokensPerResource(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	var searchedWith []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("Authorization")
+		w.Header().Set(rateLimitingResetHeader, reset)
+		if r.URL.Path == "/repos/acme/api" {
+			remaining := "4999"
+			if token == "Bearer ghp_second" {
+				remaining = "100"
+			}
+			w.Header().Set(rateLimitingResourceHeader, object.ResourceCore)
+			w.Header().Set(rateLimitingRemainingHeader, remaining)
+			_, _ = w.Write([]byte(`{"name": "api", "owner": {"login": "acme"}}`))
+			return
+		}
+
+		searchedWith = append(searchedWith, token)
+		w.Header().Set(rateLimitingResourceHeader, object.ResourceSearch)
+		if token == "Bearer ghp_first" {
+			w.Header().Set(rateLimitingRemainingHeader, "0")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+			return
+		}
+		w.Header().Set(rateLimitingRemainingHeader, "29")
+		_, _ = w.Write([]byte(`{"total_count": 1, "incomplete_results": false, "items": [{"name": "wallet", "owner": {"login": "acme"}}]}`))
+	}))
+	defer srv.Close()
+
+	details := NewGithub(WithBaseURL(srv.URL), WithTokens("ghp_first", "ghp_second"))
+	// both tokens have core quota known, the first the most
+	for i := 0; i < 2; i++ {
+		_, err := details.FetchRepo(context.Background(), "acme", "api")
+		require.NoError(t, err)
+	}
+
+	var found []object.Repository
+	err := details.SearchRepos(context.Background(), object.SearchQuery{Keywords: "wallet"}, func(repos []object.Repository) error {
+		found = append(found, repos...)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, []string{"Bearer ghp_first", "Bearer ghp_second"}, searchedWith)
+}
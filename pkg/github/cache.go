@@ -0,0 +1,68 @@
+package github
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/object"
+)
+
+// CacheEntry holds the validators GitHub returned for a URL.
+type CacheEntry struct {
+	ETag         string
+	LastModified string
+}
+
+// Cache stores response validators per URL so unchanged resources can be
+// requested conditionally; GitHub does not count 304 responses against the rate limit.
+type Cache interface {
+	Get(ctx context.Context, url string) (*CacheEntry, error)
+	Set(ctx context.Context, url string, entry CacheEntry) error
+}
+
+// revalidate adds If-None-Match/If-Modified-Since headers to req from the cached
+// validators for url.
+func (g github) revalidate(ctx context.Context, req *resty.Request, url string) {
+	if g.cache == nil || object.IsForceRefresh(ctx) {
+		return
+	}
+
+	entry, err := g.cache.Get(ctx, url)
+	if err != nil {
+		log.Printf("error reading http cache for %s, err %v", url, err)
+		return
+	}
+	if entry == nil {
+		return
+	}
+
+	if entry.ETag != "" {
+		req.SetHeader("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		req.SetHeader("If-Modified-Since", entry.LastModified)
+	}
+}
+
+// remember stores the validators of a successfully processed response for url.
+// It must only be called once the response has been fully handled, otherwise a
+// later 304 would hide data that was never persisted.
+func (g github) remember(ctx context.Context, url string, resp *resty.Response) {
+	if g.cache == nil || resp == nil || resp.StatusCode() != http.StatusOK {
+		return
+	}
+
+	entry := CacheEntry{
+		ETag:         resp.Header().Get("ETag"),
+		LastModified: resp.Header().Get("Last-Modified"),
+	}
+	if entry.ETag == "" && entry.LastModified == "" {
+		return
+	}
+
+	if err := g.cache.Set(ctx, url, entry); err != nil {
+		log.Printf("error writing http cache for %s, err %v", url, err)
+	}
+}
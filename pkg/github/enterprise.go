@@ -0,0 +1,115 @@
+package github
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnterpriseHost is a GitHub Enterprise Server instance served next to
+// github.com, with credentials and TLS settings of its own.
+type EnterpriseHost struct {
+	// Name identifies the instance in the environment, see EnterpriseHostsFromEnv.
+	Name string
+	// BaseURL is the REST API root of the instance, e.g. https://ghe.example/api/v3.
+	BaseURL string
+	Tokens  []string
+	App     *AppConfig
+	TLS     *tls.Config
+}
+
+// Host is the host repositories of the instance are stored under.
+func (h EnterpriseHost) Host() string {
+	return HostOf(h.BaseURL)
+}
+
+// Options configures a client for the instance. The tokens are always set, so
+// the github.com tokens of the environment are never sent to the instance.
+func (h EnterpriseHost) Options() []Option {
+	opts := []Option{WithBaseURL(h.BaseURL), WithTokens(h.Tokens...)}
+	if h.App != nil {
+		opts = append(opts, WithApp(*h.App))
+	}
+	if h.TLS != nil {
+		opts = append(opts, WithTLSConfig(h.TLS))
+	}
+	return opts
+}
+
+// EnterpriseHostsFromEnv reads the Enterprise Server instances named in the
+// comma separated GITHUB_ENTERPRISE_HOSTS. Each name configures its instance
+// through variables prefixed GITHUB_ENTERPRISE_<NAME>_, the name upper-cased:
+//
+//	BASE_URL               REST API root of the instance, required
+//	TOKENS                 optional comma separated personal access tokens
+//	CA_CERT_PATH           optional PEM bundle trusted next to the system roots
+//	INSECURE_SKIP_VERIFY   optional, true disables certificate verification
+//	APP_ID, APP_PRIVATE_KEY_PATH, APP_INSTALLATIONS, APP_INSTALLATION_ID
+//	                       optional GitHub App, as for AppConfigFromEnv
+func EnterpriseHostsFromEnv() ([]EnterpriseHost, error) {
+	var hosts []EnterpriseHost
+	for _, name := range strings.Split(os.Getenv("GITHUB_ENTERPRISE_HOSTS"), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+
+		host, err := enterpriseHostFromEnv(name)
+		if err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
+}
+
+func enterpriseHostFromEnv(name string) (EnterpriseHost, error) {
+	prefix := fmt.Sprintf("GITHUB_ENTERPRISE_%s_", strings.ToUpper(name))
+	host := EnterpriseHost{Name: name, BaseURL: os.Getenv(prefix + "BASE_URL")}
+	if host.BaseURL == "" {
+		return EnterpriseHost{}, fmt.Errorf("%sBASE_URL is required", prefix)
+	}
+
+	for _, token := range strings.Split(os.Getenv(prefix+"TOKENS"), ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			host.Tokens = append(host.Tokens, token)
+		}
+	}
+
+	var err error
+	if host.App, err = appConfigFromEnv(prefix); err != nil {
+		return EnterpriseHost{}, err
+	}
+
+	if path := os.Getenv(prefix + "CA_CERT_PATH"); path != "" {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return EnterpriseHost{}, fmt.Errorf("reading %sCA_CERT_PATH: %w", prefix, err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return EnterpriseHost{}, fmt.Errorf("%sCA_CERT_PATH holds no PEM certificates", prefix)
+		}
+		host.TLS = &tls.Config{RootCAs: roots}
+	}
+	if v := os.Getenv(prefix + "INSECURE_SKIP_VERIFY"); v != "" {
+		skip, err := strconv.ParseBool(v)
+		if err != nil {
+			return EnterpriseHost{}, fmt.Errorf("invalid %sINSECURE_SKIP_VERIFY: %w", prefix, err)
+		}
+		if skip {
+			if host.TLS == nil {
+				host.TLS = &tls.Config{}
+			}
+			host.TLS.InsecureSkipVerify = true
+		}
+	}
+
+	return host, nil
+}
@@ -0,0 +1,52 @@
+package github
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnterpriseHostFromEnv(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v3/repos/acme/api", r.URL.Path)
+		assert.Equal(t, "Bearer ghe-token", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"name": "api", "owner": {"login": "acme"}}`))
+	}))
+	defer srv.Close()
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caPath, caPEM, 0o600))
+
+	t.Setenv("GITHUB_TOKEN", "github-com-token")
+	t.Setenv("GITHUB_ENTERPRISE_HOSTS", "ghe")
+	t.Setenv("GITHUB_ENTERPRISE_GHE_BASE_URL", srv.URL+"/api/v3")
+	t.Setenv("GITHUB_ENTERPRISE_GHE_TOKENS", "ghe-token")
+	t.Setenv("GITHUB_ENTERPRISE_GHE_CA_CERT_PATH", caPath)
+
+	hosts, err := EnterpriseHostsFromEnv()
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	assert.Equal(t, strings.TrimPrefix(srv.URL, "https://"), hosts[0].Host())
+
+	details := NewGithub(hosts[0].Options()...)
+	repo, err := details.FetchRepo(context.Background(), "acme", "api")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", repo.Owner)
+
+	assert.Equal(t, "https://ghe.example/api/graphql", graphQL{github: github{baseURL: "https://ghe.example/api/v3"}}.graphQLURL())
+}
+
+func TestHostOf(t *testing.T) {
+	assert.Equal(t, "github.com", HostOf(""))
+	assert.Equal(t, "github.com", HostOf("https://api.github.com"))
+	assert.Equal(t, "ghe.example", HostOf("https://GHE.example/api/v3"))
+}
@@ -0,0 +1,89 @@
+package github
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/object"
+)
+
+const (
+	retryAfterHeader = "Retry-After"
+	// defaultSecondaryRetryAfter is how long GitHub asks clients to back off after
+	// a secondary rate limit that comes without a Retry-After header.
+	defaultSecondaryRetryAfter = time.Minute
+)
+
+// checkResponse turns an unsuccessful response into one of the typed errors of
+// package object. resource names what was requested, for NotFoundError.
+func checkResponse(resp *resty.Response, resource string) error {
+	if resp.IsSuccess() {
+		return nil
+	}
+
+	status := resp.StatusCode()
+	switch status {
+	case http.StatusNotFound:
+		return &object.NotFoundError{Resource: resource}
+	case http.StatusUnauthorized:
+		return &object.UnauthorizedError{StatusCode: status, Message: errorMessage(resp)}
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		if isPrimaryRateLimit(resp) {
+			return &object.RateLimitError{Reset: resetTime(resp.Header())}
+		}
+		if retryAfter, ok := secondaryRateLimit(resp); ok {
+			return &object.SecondaryRateLimitError{RetryAfter: retryAfter}
+		}
+		if status == http.StatusForbidden {
+			return &object.UnauthorizedError{StatusCode: status, Message: errorMessage(resp)}
+		}
+	}
+
+	return &object.UpstreamError{StatusCode: status, Body: string(resp.Body())}
+}
+
+// isPrimaryRateLimit reports whether resp was rejected because the quota of the
+// credential is exhausted.
+func isPrimaryRateLimit(resp *resty.Response) bool {
+	status := resp.StatusCode()
+	return (status == http.StatusForbidden || status == http.StatusTooManyRequests) &&
+		resp.Header().Get(rateLimitingRemainingHeader) == "0"
+}
+
+// secondaryRateLimit detects GitHub's abuse throttling, which is signalled by a
+// Retry-After header or by the error message alone.
+func secondaryRateLimit(resp *resty.Response) (time.Duration, bool) {
+	if seconds, err := strconv.Atoi(resp.Header().Get(retryAfterHeader)); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if strings.Contains(strings.ToLower(errorMessage(resp)), "secondary rate limit") {
+		return defaultSecondaryRetryAfter, true
+	}
+
+	return 0, false
+}
+
+// resetTime reads X-RateLimit-Reset, assuming an hour when it is missing.
+func resetTime(header http.Header) time.Time {
+	reset, err := strconv.ParseInt(header.Get(rateLimitingResetHeader), 10, 64)
+	if err != nil {
+		return time.Now().Add(time.Hour)
+	}
+	return time.Unix(reset, 0)
+}
+
+// errorMessage extracts the message field of a GitHub error body.
+func errorMessage(resp *resty.Response) string {
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil || body.Message == "" {
+		return string(resp.Body())
+	}
+	return body.Message
+}
@@ -0,0 +1,98 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchRepoTypedErrors(t *testing.T) {
+	reset := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name   string
+		status int
+		header map[string]string
+		body   string
+		check  func(t *testing.T, err error)
+	}{
+		{
+			name:   "not found",
+			status: http.StatusNotFound,
+			body:   `{"message": "Not Found"}`,
+			check: func(t *testing.T, err error) {
+				var target *object.NotFoundError
+				assert.True(t, errors.As(err, &target))
+			},
+		},
+		{
+			name:   "bad credentials",
+			status: http.StatusUnauthorized,
+			body:   `{"message": "Bad credentials"}`,
+			check: func(t *testing.T, err error) {
+				var target *object.UnauthorizedError
+				assert.True(t, errors.As(err, &target))
+				assert.Equal(t, "Bad credentials", target.Message)
+			},
+		},
+		{
+			name:   "primary rate limit",
+			status: http.StatusForbidden,
+			header: map[string]string{
+				rateLimitingRemainingHeader: "0",
+				rateLimitingResetHeader:     strconv.FormatInt(reset.Unix(), 10),
+			},
+			check: func(t *testing.T, err error) {
+				var target *object.RateLimitError
+				assert.True(t, errors.As(err, &target))
+				assert.True(t, target.Reset.Equal(reset))
+			},
+		},
+		{
+			name:   "secondary rate limit",
+			status: http.StatusForbidden,
+			header: map[string]string{retryAfterHeader: "42"},
+			body:   `{"message": "You have exceeded a secondary rate limit."}`,
+			check: func(t *testing.T, err error) {
+				var target *object.SecondaryRateLimitError
+				assert.True(t, errors.As(err, &target))
+				assert.Equal(t, 42*time.Second, target.RetryAfter)
+			},
+		},
+		{
+			name:   "validation failed",
+			status: http.StatusUnprocessableEntity,
+			body:   `{"message": "Validation Failed"}`,
+			check: func(t *testing.T, err error) {
+				var target *object.UpstreamError
+				assert.True(t, errors.As(err, &target))
+				assert.Equal(t, http.StatusUnprocessableEntity, target.StatusCode)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			t.Setenv("GITHUB_BASE_URL", srv.URL)
+
+			repo, err := NewGithub(WithTokens()).FetchRepo(context.Background(), "octo", "hello")
+			assert.Nil(t, repo)
+			tt.check(t, err)
+		})
+	}
+}
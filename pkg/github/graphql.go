@@ -0,0 +1,477 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/object"
+)
+
+const (
+	// graphQLPageSize is the largest page GitHub's GraphQL connections return.
+	graphQLPageSize = 100
+	// graphQLBatchSize bounds how many repositories FetchRepos puts in one query,
+	// keeping each query well under GitHub's node limit.
+	graphQLBatchSize = 25
+)
+
+const (
+	graphQLRateLimit = `rateLimit { cost limit remaining resetAt }`
+
+	graphQLRepoFields = `fragment repoFields on Repository {
+	name
+	owner { login }
+	description
+	url
+	primaryLanguage { name }
+	forkCount
+	stargazerCount
+	issues(states: OPEN) { totalCount }
+	watchers { totalCount }
+	createdAt
+	updatedAt
+	isFork
+	isArchived
+}`
+
+	graphQLHistoryFields = `fragment historyFields on CommitHistoryConnection {
+	pageInfo { hasNextPage endCursor }
+	nodes { oid message committedDate author { name email date } }
+}`
+)
+
+// graphQL implements object.GitDetails on top of GitHub's GraphQL v4 API, sharing
+// authentication and quota tracking with the REST client it wraps.
+type graphQL struct {
+	github
+}
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type graphQLResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+type graphQLRepository struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	Description     string `json:"description"`
+	URL             string `json:"url"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	ForkCount      int `json:"forkCount"`
+	StargazerCount int `json:"stargazerCount"`
+	Issues         struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"issues"`
+	Watchers struct {
+		TotalCount int `json:"totalCount"`
+	} `json:"watchers"`
+	CreatedAt        string `json:"createdAt"`
+	UpdatedAt        string `json:"updatedAt"`
+	IsFork           bool   `json:"isFork"`
+	IsArchived       bool   `json:"isArchived"`
+	DefaultBranchRef *struct {
+		Target struct {
+			History *graphQLHistory `json:"history"`
+		} `json:"target"`
+	} `json:"defaultBranchRef"`
+}
+
+type graphQLHistory struct {
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+	Nodes []struct {
+		Oid           string    `json:"oid"`
+		Message       string    `json:"message"`
+		CommittedDate time.Time `json:"committedDate"`
+		Author        struct {
+			Name  string    `json:"name"`
+			Email string    `json:"email"`
+			Date  time.Time `json:"date"`
+		} `json:"author"`
+	} `json:"nodes"`
+}
+
+func (r graphQLRepository) toObject() object.Repository {
+	repository := object.Repository{
+		Name:            r.Name,
+		Owner:           r.Owner.Login,
+		Description:     r.Description,
+		URL:             r.URL,
+		ForksCount:      r.ForkCount,
+		StarsCount:      r.StargazerCount,
+		OpenIssuesCount: r.Issues.TotalCount,
+		WatchersCount:   r.Watchers.TotalCount,
+		CreatedAt:       r.CreatedAt,
+		UpdatedAt:       r.UpdatedAt,
+		Fork:            r.IsFork,
+		Archived:        r.IsArchived,
+	}
+	if r.PrimaryLanguage != nil {
+		repository.Language = r.PrimaryLanguage.Name
+	}
+
+	return repository
+}
+
+// history returns the commit history of the default branch, nil for empty repositories.
+func (r graphQLRepository) history() *graphQLHistory {
+	if r.DefaultBranchRef == nil {
+		return nil
+	}
+	return r.DefaultBranchRef.Target.History
+}
+
+func (h *graphQLHistory) commits() []object.Commit {
+	commits := make([]object.Commit, 0, len(h.Nodes))
+	for _, node := range h.Nodes {
+		commits = append(commits, object.Commit{
+			SHA:         node.Oid,
+			AuthorName:  node.Author.Name,
+			AuthorEmail: node.Author.Email,
+			Message:     node.Message,
+			Date:        node.Author.Date,
+			CommittedAt: node.CommittedDate,
+		})
+	}
+
+	return commits
+}
+
+// graphQLURL is the GraphQL endpoint of the host, which Enterprise Server
+// serves at /api/graphql next to the /api/v3 REST root.
+func (g graphQL) graphQLURL() string {
+	if root, ok := strings.CutSuffix(g.baseURL, "/api/v3"); ok {
+		return root + "/api/graphql"
+	}
+	return g.baseURL + "/graphql"
+}
+
+// query runs a GraphQL query on behalf of owner and records the rateLimit block
+// of the response against the credential that paid for it.
+func (g graphQL) query(ctx context.Context, owner, query string, variables map[string]interface{}) (*graphQLResponse, error) {
+	resp, credentialID, err := g.send(ctx, resty.MethodPost, owner, g.graphQLURL(),
+		graphQLRequest{Query: query, Variables: variables}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkResponse(resp, "graphql endpoint"); err != nil {
+		return nil, err
+	}
+
+	var response graphQLResponse
+	if err := json.Unmarshal(resp.Body(), &response); err != nil {
+		return nil, err
+	}
+
+	var rateLimit struct {
+		Cost      int       `json:"cost"`
+		Limit     int       `json:"limit"`
+		Remaining int       `json:"remaining"`
+		ResetAt   time.Time `json:"resetAt"`
+	}
+	if raw, ok := response.Data["rateLimit"]; ok {
+		if err := json.Unmarshal(raw, &rateLimit); err == nil {
+			g.quota.record(object.RateLimit{
+				Credential: credentialID,
+				Resource:   object.ResourceGraphQL,
+				Limit:      rateLimit.Limit,
+				Remaining:  rateLimit.Remaining,
+				Reset:      rateLimit.ResetAt,
+				Cost:       rateLimit.Cost,
+			})
+		}
+	}
+
+	for _, e := range response.Errors {
+		switch e.Type {
+		case "RATE_LIMITED":
+			reset := rateLimit.ResetAt
+			if reset.IsZero() {
+				reset = resetTime(resp.Header())
+			}
+			return nil, &object.RateLimitError{Reset: reset}
+		case "FORBIDDEN":
+			return nil, &object.UnauthorizedError{StatusCode: http.StatusForbidden, Message: e.Message}
+		}
+	}
+
+	if response.Data == nil && len(response.Errors) > 0 {
+		return nil, &object.UpstreamError{StatusCode: resp.StatusCode(), Body: response.Errors[0].Message}
+	}
+
+	return &response, nil
+}
+
+// SearchRepos hands every repository matching search to handle, following the
+// search connection's cursors and slicing searches above its result cap.
+func (g graphQL) SearchRepos(ctx context.Context, search object.SearchQuery, handle object.RepoPageFunc) error {
+	text, err := searchText(search)
+	if err != nil {
+		return err
+	}
+	// the GraphQL search takes ordering as a qualifier of the query text
+	if search.Sort != "" {
+		order := search.Order
+		if order == "" {
+			order = "desc"
+		}
+		text += fmt.Sprintf(" sort:%s-%s", search.Sort, order)
+	}
+
+	query := fmt.Sprintf(`query($q: String!, $after: String) {
+	%s
+	search(query: $q, type: REPOSITORY, first: %d, after: $after) {
+		repositoryCount
+		pageInfo { hasNextPage endCursor }
+		nodes { ... on Repository { ...repoFields } }
+	}
+}
+%s`, graphQLRateLimit, graphQLPageSize, graphQLRepoFields)
+
+	fetch := func(ctx context.Context, q, cursor string) (searchPage, error) {
+		variables := map[string]interface{}{"q": q, "after": nil}
+		if cursor != "" {
+			variables["after"] = cursor
+		}
+
+		response, err := g.query(ctx, "", query, variables)
+		if err != nil {
+			return searchPage{}, err
+		}
+
+		var result struct {
+			RepositoryCount int `json:"repositoryCount"`
+			PageInfo        struct {
+				HasNextPage bool   `json:"hasNextPage"`
+				EndCursor   string `json:"endCursor"`
+			} `json:"pageInfo"`
+			Nodes []graphQLRepository `json:"nodes"`
+		}
+		if err := json.Unmarshal(response.Data["search"], &result); err != nil {
+			return searchPage{}, err
+		}
+
+		page := searchPage{
+			repos: make([]object.Repository, 0, len(result.Nodes)),
+			total: result.RepositoryCount,
+		}
+		if result.PageInfo.HasNextPage {
+			page.next = result.PageInfo.EndCursor
+		}
+		for _, node := range result.Nodes {
+			page.repos = append(page.repos, node.toObject())
+		}
+
+		return page, nil
+	}
+
+	return searchAll(ctx, text, fetch, handle)
+}
+
+func (g graphQL) FetchRepo(ctx context.Context, owner, repo string) (*object.Repository, error) {
+	query := fmt.Sprintf(`query($owner: String!, $name: String!) {
+	%s
+	repository(owner: $owner, name: $name) { ...repoFields }
+}
+%s`, graphQLRateLimit, graphQLRepoFields)
+
+	response, err := g.query(ctx, owner, query, map[string]interface{}{"owner": owner, "name": repo})
+	if err != nil {
+		return nil, err
+	}
+
+	var repository *graphQLRepository
+	if err := json.Unmarshal(response.Data["repository"], &repository); err != nil {
+		return nil, err
+	}
+	if repository == nil {
+		return nil, &object.NotFoundError{Resource: fmt.Sprintf("repository %s/%s", owner, repo)}
+	}
+
+	result := repository.toObject()
+	return &result, nil
+}
+
+func (g graphQL) FetchCommits(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error {
+	query := fmt.Sprintf(`query($owner: String!, $name: String!, $after: String, $since: GitTimestamp, $until: GitTimestamp) {
+	%s
+	repository(owner: $owner, name: $name) {
+		defaultBranchRef { target { ... on Commit {
+			history(first: %d, after: $after, since: $since, until: $until) { ...historyFields }
+		} } }
+	}
+}
+%s`, graphQLRateLimit, graphQLPageSize, graphQLHistoryFields)
+
+	variables := map[string]interface{}{
+		"owner": owner,
+		"name":  repo,
+		"since": gitTimestamp(opts.Since),
+		"until": gitTimestamp(opts.Until),
+	}
+
+	for page := 1; ; page++ {
+		if g.maxCommitPages > 0 && page > g.maxCommitPages {
+			return object.ErrTruncated
+		}
+
+		response, err := g.query(ctx, owner, query, variables)
+		if err != nil {
+			return err
+		}
+
+		var repository *graphQLRepository
+		if err := json.Unmarshal(response.Data["repository"], &repository); err != nil {
+			return err
+		}
+		if repository == nil {
+			return &object.NotFoundError{Resource: fmt.Sprintf("repository %s/%s", owner, repo)}
+		}
+
+		history := repository.history()
+		if history == nil {
+			return nil
+		}
+
+		if commits := history.commits(); len(commits) > 0 {
+			if err := handle(commits); err != nil {
+				return err
+			}
+		}
+
+		if !history.PageInfo.HasNextPage {
+			return nil
+		}
+		variables["after"] = history.PageInfo.EndCursor
+	}
+}
+
+// FetchRepos fetches the metadata and first page of commit history of refs,
+// putting up to graphQLBatchSize repositories in each query. Each query only
+// holds repositories whose owners authenticate with the same credential, so
+// that a GitHub App installation is never asked for repositories it cannot see.
+func (g graphQL) FetchRepos(ctx context.Context, refs []object.RepoRef) ([]object.RepoSnapshot, error) {
+	groups, err := g.byCredential(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]object.RepoSnapshot, 0, len(refs))
+	for _, group := range groups {
+		for start := 0; start < len(group); start += graphQLBatchSize {
+			end := start + graphQLBatchSize
+			if end > len(group) {
+				end = len(group)
+			}
+			batch := group[start:end]
+
+			query, variables := batchQuery(batch)
+			response, err := g.query(ctx, batch[0].Owner, query, variables)
+			if err != nil {
+				return nil, err
+			}
+
+			for i := range batch {
+				var repository *graphQLRepository
+				if err := json.Unmarshal(response.Data[fmt.Sprintf("r%d", i)], &repository); err != nil {
+					return nil, err
+				}
+				if repository == nil {
+					continue
+				}
+
+				snapshot := object.RepoSnapshot{Repository: repository.toObject()}
+				if history := repository.history(); history != nil {
+					snapshot.Commits = history.commits()
+					snapshot.HasMoreCommits = history.PageInfo.HasNextPage
+				}
+				snapshots = append(snapshots, snapshot)
+			}
+		}
+	}
+
+	return snapshots, nil
+}
+
+// byCredential groups refs by the credential their owner authenticates with,
+// keeping their order. Owners no installation covers are left out.
+func (g graphQL) byCredential(ctx context.Context, refs []object.RepoRef) ([][]object.RepoRef, error) {
+	var (
+		groups [][]object.RepoRef
+		index  = make(map[string]int)
+	)
+	for _, ref := range refs {
+		cred, err := g.auth.credential(ctx, ref.Owner, object.ResourceGraphQL, nil)
+		if err != nil {
+			var notFound *object.NotFoundError
+			if errors.As(err, &notFound) {
+				log.Printf("skipping %s/%s: %v", ref.Owner, ref.Name, err)
+				continue
+			}
+			return nil, err
+		}
+
+		i, ok := index[cred.id]
+		if !ok {
+			i = len(groups)
+			index[cred.id] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], ref)
+	}
+
+	return groups, nil
+}
+
+// batchQuery builds one query fetching every ref under the alias r<index>.
+func batchQuery(refs []object.RepoRef) (string, map[string]interface{}) {
+	var (
+		params  []string
+		fields  strings.Builder
+		vars    = make(map[string]interface{}, len(refs)*4)
+		history = fmt.Sprintf("history(first: %d, since: $s%%[1]d, until: $u%%[1]d) { ...historyFields }", graphQLPageSize)
+	)
+	for i, ref := range refs {
+		params = append(params, fmt.Sprintf("$o%[1]d: String!, $n%[1]d: String!, $s%[1]d: GitTimestamp, $u%[1]d: GitTimestamp", i))
+		fmt.Fprintf(&fields, "\tr%[1]d: repository(owner: $o%[1]d, name: $n%[1]d) {\n\t\t...repoFields\n\t\tdefaultBranchRef { target { ... on Commit { "+history+" } } }\n\t}\n", i)
+
+		vars[fmt.Sprintf("o%d", i)] = ref.Owner
+		vars[fmt.Sprintf("n%d", i)] = ref.Name
+		vars[fmt.Sprintf("s%d", i)] = gitTimestamp(ref.Commits.Since)
+		vars[fmt.Sprintf("u%d", i)] = gitTimestamp(ref.Commits.Until)
+	}
+
+	query := fmt.Sprintf("query(%s) {\n\t%s\n%s}\n%s\n%s",
+		strings.Join(params, ", "), graphQLRateLimit, fields.String(), graphQLRepoFields, graphQLHistoryFields)
+	return query, vars
+}
+
+// gitTimestamp formats t as a GraphQL GitTimestamp, leaving zero times unset.
+func gitTimestamp(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,116 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQLFetchReposBatchesRepositories(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var req graphQLRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "octo", req.Variables["o0"])
+		assert.Equal(t, "2024-07-01T00:00:00Z", req.Variables["s0"])
+		assert.Nil(t, req.Variables["s1"])
+
+		_, _ = w.Write([]byte(`{"data": {
+			"rateLimit": {"cost": 1, "limit": 5000, "remaining": 4999, "resetAt": "2030-01-01T00:00:00Z"},
+			"r0": {"name": "hello", "owner": {"login": "octo"}, "primaryLanguage": {"name": "Go"}, "stargazerCount": 7,
+				"defaultBranchRef": {"target": {"history": {
+					"pageInfo": {"hasNextPage": true, "endCursor": "abc"},
+					"nodes": [{"oid": "c1", "message": "init", "author": {"name": "Octo", "email": "o@x", "date": "2024-07-02T00:00:00Z"}}]
+				}}}},
+			"r1": null
+		}, "errors": [{"type": "NOT_FOUND", "message": "Could not resolve to a Repository"}]}`))
+	}))
+	defer srv.Close()
+	t.Setenv("GITHUB_BASE_URL", srv.URL)
+
+	details := NewGithub(WithGraphQL(), WithTokens("ghp_test"))
+	batcher, ok := details.(object.BatchFetcher)
+	require.True(t, ok)
+
+	snapshots, err := batcher.FetchRepos(context.Background(), []object.RepoRef{
+		{Owner: "octo", Name: "hello", Commits: object.CommitOptions{Since: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)}},
+		{Owner: "octo", Name: "gone"},
+	})
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, "Go", snapshots[0].Language)
+	assert.Equal(t, 7, snapshots[0].StarsCount)
+	assert.True(t, snapshots[0].HasMoreCommits)
+	require.Len(t, snapshots[0].Commits, 1)
+	assert.Equal(t, "c1", snapshots[0].Commits[0].SHA)
+
+	limits := details.(object.RateLimitReporter).RateLimits()
+	require.Len(t, limits, 1)
+	assert.Equal(t, object.ResourceGraphQL, limits[0].Resource)
+	assert.Equal(t, 1, limits[0].Cost)
+}
+
+func TestGraphQLFetchReposBatchesByInstallation(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var (
+		mu      sync.Mutex
+		queried = make(map[string][]string)
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /app/installations/{id}/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"token": "ghs_" + r.PathValue("id"), "expires_at": time.Now().Add(time.Hour)})
+	})
+	mux.HandleFunc("POST /graphql", func(w http.ResponseWriter, r *http.Request) {
+		var req graphQLRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		data := map[string]any{}
+		for i := 0; ; i++ {
+			owner, ok := req.Variables[fmt.Sprintf("o%d", i)].(string)
+			if !ok {
+				break
+			}
+			mu.Lock()
+			queried[r.Header.Get("Authorization")] = append(queried[r.Header.Get("Authorization")], owner)
+			mu.Unlock()
+			data[fmt.Sprintf("r%d", i)] = map[string]any{"name": req.Variables[fmt.Sprintf("n%d", i)], "owner": map[string]any{"login": owner}}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": data})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	details := NewGithub(WithBaseURL(srv.URL), WithGraphQL(), WithApp(AppConfig{
+		AppID:         1,
+		PrivateKey:    key,
+		Installations: map[string]int64{"octo": 42, "acme": 43},
+	}))
+	snapshots, err := details.(object.BatchFetcher).FetchRepos(context.Background(), []object.RepoRef{
+		{Owner: "octo", Name: "hello"},
+		{Owner: "acme", Name: "api"},
+		{Owner: "octo", Name: "world"},
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, snapshots, 3)
+	assert.Equal(t, map[string][]string{
+		"token ghs_42": {"octo", "octo"},
+		"token ghs_43": {"acme"},
+	}, queried)
+}
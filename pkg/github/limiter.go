@@ -0,0 +1,179 @@
+package github
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+// burstDivisor sets how much of a limit may be spent at once: a twentieth, so
+// 250 of the 5,000 core requests an hour but a single search at a time.
+const burstDivisor = 20
+
+// bucket is a token bucket pacing the requests of one credential to one
+// rate-limit resource. It refills at the rate that spends the remaining quota
+// evenly until the reset, and holds at most a burst of tokens.
+type bucket struct {
+	tokens    float64
+	burst     float64
+	rate      float64 // tokens per second
+	cost      float64 // tokens a request takes
+	updated   time.Time
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+// limiter paces requests by the quota GitHub reported in the X-RateLimit
+// headers of previous responses. Buckets it knows nothing about, or whose
+// window has reset, do not hold requests back.
+type limiter struct {
+	mu      sync.Mutex
+	buckets map[quotaKey]*bucket
+	now     func() time.Time
+}
+
+func newLimiter() *limiter {
+	return &limiter{buckets: make(map[quotaKey]*bucket), now: time.Now}
+}
+
+// update resets the pace of the bucket of limit to its latest quota.
+func (l *limiter) update(limit object.RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	key := quotaKey{credential: limit.Credential, resource: limit.Resource}
+	b, found := l.buckets[key]
+	if !found {
+		b = &bucket{updated: now, cost: 1}
+		l.buckets[key] = b
+	}
+	b.refill(now)
+
+	b.limit = limit.Limit
+	b.remaining = limit.Remaining
+	b.reset = limit.Reset
+	// only the GraphQL body reports what a query cost, its headers do not
+	if limit.Cost > 0 {
+		b.cost = float64(limit.Cost)
+	}
+	b.burst = math.Max(1, math.Min(float64(limit.Limit/burstDivisor), float64(limit.Remaining)))
+	if window := b.reset.Sub(now).Seconds(); window > 0 {
+		b.rate = float64(limit.Remaining) / window
+	} else {
+		b.rate = 0
+	}
+	if !found || b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// reserve takes the tokens of a request from the bucket of credential id and
+// resource, and returns how long the request has to wait for them. Waiting
+// requests go into debt so that concurrent callers queue up behind each other.
+func (l *limiter) reserve(id, resource string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, found := l.buckets[quotaKey{credential: id, resource: resource}]
+	// an exhausted quota is not paced but rejected by GitHub, which lets the
+	// caller switch credentials or wait for the reset
+	if !found || b.rate <= 0 || b.remaining <= 0 || !now.Before(b.reset) {
+		return 0
+	}
+
+	b.refill(now)
+	b.tokens -= b.cost
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	wait := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	if untilReset := b.reset.Sub(now); wait > untilReset {
+		wait = untilReset
+	}
+	return wait
+}
+
+// cancel gives back the tokens of a request reserved but not sent.
+func (l *limiter) cancel(id, resource string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, found := l.buckets[quotaKey{credential: id, resource: resource}]; found {
+		b.tokens = math.Min(b.burst, b.tokens+b.cost)
+	}
+}
+
+// wait blocks until a request of credential id to resource may be sent. When
+// ctx may not wait, see object.WithoutWaiting, it fails straight away with the
+// delay as a SecondaryRateLimitError instead.
+func (l *limiter) wait(ctx context.Context, id, resource string) error {
+	wait := l.reserve(id, resource)
+	if wait <= 0 {
+		return nil
+	}
+	if !object.IsWaitingAllowed(ctx) {
+		l.cancel(id, resource)
+		return &object.SecondaryRateLimitError{RetryAfter: wait}
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// budget sums the buckets of resource over every credential whose window has
+// not reset yet.
+func (l *limiter) budget(resource string) (object.Budget, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	budget := object.Budget{Resource: resource, Cost: 1}
+	found := false
+	for key, b := range l.buckets {
+		if key.resource != resource || !now.Before(b.reset) {
+			continue
+		}
+		found = true
+		budget.Limit += b.limit
+		budget.Remaining += b.remaining
+		budget.Rate += b.rate / b.cost
+		budget.Cost = max(budget.Cost, int(math.Ceil(b.cost)))
+		if b.reset.After(budget.Reset) {
+			budget.Reset = b.reset
+		}
+	}
+	return budget, found
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+	}
+	b.updated = now
+}
+
+// resourceOf names the rate-limit resource a request to url is counted against.
+func resourceOf(url string) string {
+	switch {
+	case strings.Contains(url, "/search/"):
+		return object.ResourceSearch
+	case strings.HasSuffix(url, "/graphql"):
+		return object.ResourceGraphQL
+	default:
+		return object.ResourceCore
+	}
+}
@@ -0,0 +1,118 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterPacesUntilReset(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	l := newLimiter()
+	l.now = func() time.Time { return now }
+
+	// 10 requests left over 10 seconds: one a second, after a burst of 5
+	l.update(object.RateLimit{Credential: "a", Resource: object.ResourceCore, Limit: 100, Remaining: 10, Reset: now.Add(10 * time.Second)})
+
+	for i := 0; i < 5; i++ {
+		assert.Zero(t, l.reserve("a", object.ResourceCore))
+	}
+	assert.Equal(t, time.Second, l.reserve("a", object.ResourceCore))
+	assert.Equal(t, 2*time.Second, l.reserve("a", object.ResourceCore))
+
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, time.Second, l.reserve("a", object.ResourceCore))
+
+	// other resources and credentials are budgeted separately
+	assert.Zero(t, l.reserve("a", object.ResourceSearch))
+	assert.Zero(t, l.reserve("b", object.ResourceCore))
+}
+
+func TestLimiterReleasesAfterReset(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	l := newLimiter()
+	l.now = func() time.Time { return now }
+
+	l.update(object.RateLimit{Credential: "a", Resource: object.ResourceSearch, Limit: 30, Remaining: 1, Reset: now.Add(time.Minute)})
+	assert.Zero(t, l.reserve("a", object.ResourceSearch))
+	assert.Equal(t, time.Minute, l.reserve("a", object.ResourceSearch))
+
+	now = now.Add(time.Minute)
+	assert.Zero(t, l.reserve("a", object.ResourceSearch))
+}
+
+func TestLimiterFailsFastWithoutWaiting(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	l := newLimiter()
+	l.now = func() time.Time { return now }
+
+	l.update(object.RateLimit{Credential: "a", Resource: object.ResourceCore, Limit: 100, Remaining: 1, Reset: now.Add(time.Hour)})
+	ctx := object.WithoutWaiting(context.Background())
+	require.NoError(t, l.wait(ctx, "a", object.ResourceCore))
+
+	err := l.wait(ctx, "a", object.ResourceCore)
+	var secondary *object.SecondaryRateLimitError
+	require.ErrorAs(t, err, &secondary)
+	assert.Equal(t, time.Hour, secondary.RetryAfter)
+
+	// the refused request did not go into debt: half a token refilled since,
+	// and the next request waits for the other half only
+	now = now.Add(time.Hour / 2)
+	l.update(object.RateLimit{Credential: "a", Resource: object.ResourceCore, Limit: 100, Remaining: 10, Reset: now.Add(time.Hour)})
+	assert.Equal(t, 3*time.Minute, l.reserve("a", object.ResourceCore))
+}
+
+func TestLimiterBudget(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	l := newLimiter()
+	l.now = func() time.Time { return now }
+
+	_, ok := l.budget(object.ResourceCore)
+	assert.False(t, ok)
+
+	l.update(object.RateLimit{Credential: "a", Resource: object.ResourceCore, Limit: 5000, Remaining: 3600, Reset: now.Add(time.Hour)})
+	l.update(object.RateLimit{Credential: "b", Resource: object.ResourceCore, Limit: 5000, Remaining: 1800, Reset: now.Add(30 * time.Minute)})
+	l.update(object.RateLimit{Credential: "a", Resource: object.ResourceSearch, Limit: 30, Remaining: 30, Reset: now.Add(time.Minute)})
+
+	budget, ok := l.budget(object.ResourceCore)
+	require.True(t, ok)
+	assert.Equal(t, 10000, budget.Limit)
+	assert.Equal(t, 5400, budget.Remaining)
+	assert.Equal(t, now.Add(time.Hour), budget.Reset)
+	assert.InDelta(t, 2.0, budget.Rate, 0.001)
+	assert.Equal(t, 1, budget.Cost)
+
+	// a graphql query is expected to cost what the last one did
+	l.update(object.RateLimit{Credential: "a", Resource: object.ResourceGraphQL, Limit: 5000, Remaining: 4950, Reset: now.Add(time.Hour), Cost: 50})
+	budget, ok = l.budget(object.ResourceGraphQL)
+	require.True(t, ok)
+	assert.Equal(t, 50, budget.Cost)
+}
+
+func TestBudgetFromResponseHeaders(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Unix()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(rateLimitingLimitHeader, "5000")
+		w.Header().Set(rateLimitingRemainingHeader, "4999")
+		w.Header().Set(rateLimitingResetHeader, strconv.FormatInt(reset, 10))
+		w.Header().Set(rateLimitingResourceHeader, object.ResourceCore)
+		_, _ = w.Write([]byte(`{"name": "api", "owner": {"login": "acme"}}`))
+	}))
+	defer srv.Close()
+
+	details := NewGithub(WithBaseURL(srv.URL), WithTokens("ghp_token"))
+	_, err := details.FetchRepo(context.Background(), "acme", "api")
+	require.NoError(t, err)
+
+	budget, ok := details.(object.BudgetReporter).Budget(object.ResourceCore)
+	require.True(t, ok)
+	assert.Equal(t, 4999, budget.Remaining)
+	assert.Equal(t, reset, budget.Reset.Unix())
+}
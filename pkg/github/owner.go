@@ -0,0 +1,77 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/project/pkg/object"
+)
+
+// reposPerPage is the largest page size GitHub accepts for repository listings.
+const reposPerPage = 100
+
+// ListOrgRepos hands every repository of org to handle, forks and archived
+// repositories included.
+func (g github) ListOrgRepos(ctx context.Context, org string, handle object.RepoPageFunc) error {
+	query := url.Values{}
+	query.Set("type", "all")
+	query.Set("per_page", strconv.Itoa(reposPerPage))
+	listURL := fmt.Sprintf("%s/orgs/%s/repos?%s", g.baseURL, url.PathEscape(org), query.Encode())
+
+	return g.listRepos(ctx, org, listURL, fmt.Sprintf("organization %s", org), handle)
+}
+
+// ListUserRepos hands every repository owned by user to handle, forks and
+// archived repositories included.
+func (g github) ListUserRepos(ctx context.Context, user string, handle object.RepoPageFunc) error {
+	query := url.Values{}
+	query.Set("type", "owner")
+	query.Set("per_page", strconv.Itoa(reposPerPage))
+	listURL := fmt.Sprintf("%s/users/%s/repos?%s", g.baseURL, url.PathEscape(user), query.Encode())
+
+	return g.listRepos(ctx, user, listURL, fmt.Sprintf("user %s", user), handle)
+}
+
+// listRepos follows the Link pagination of a repository listing. Listings are
+// always requested in full, since callers compare them against what they stored;
+// rate limits hit mid-listing are waited out through the waiter of ctx.
+func (g github) listRepos(ctx context.Context, owner, listURL, resource string, handle object.RepoPageFunc) error {
+	for pageURL := listURL; pageURL != ""; {
+		resp, err := g.get(ctx, owner, pageURL, false)
+		if err == nil {
+			err = checkResponse(resp, resource)
+		}
+		if err != nil {
+			retry, waitErr := object.Wait(ctx, err)
+			if waitErr != nil {
+				return waitErr
+			}
+			if retry {
+				continue
+			}
+			return err
+		}
+
+		var repos []Repository
+		if err := json.Unmarshal(resp.Body(), &repos); err != nil {
+			return err
+		}
+
+		page := make([]object.Repository, 0, len(repos))
+		for _, rr := range repos {
+			page = append(page, rr.toObject())
+		}
+		if len(page) > 0 {
+			if err := handle(page); err != nil {
+				return err
+			}
+		}
+
+		pageURL = nextPageURL(resp.Header().Get(linkHeader))
+	}
+
+	return nil
+}
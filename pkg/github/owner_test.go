@@ -0,0 +1,42 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListOrgReposFollowsPagination(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/orgs/acme/repos", r.URL.Path)
+		assert.Equal(t, "all", r.URL.Query().Get("type"))
+
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set(linkHeader, fmt.Sprintf(`<%s/orgs/acme/repos?type=all&page=2>; rel="next"`, srv.URL))
+			_, _ = w.Write([]byte(`[{"name": "api", "owner": {"login": "acme"}}, {"name": "fork", "owner": {"login": "acme"}, "fork": true}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"name": "legacy", "owner": {"login": "acme"}, "archived": true}]`))
+	}))
+	defer srv.Close()
+	t.Setenv("GITHUB_BASE_URL", srv.URL)
+
+	var repos []object.Repository
+	err := NewGithub(WithTokens()).ListOrgRepos(context.Background(), "acme", func(page []object.Repository) error {
+		repos = append(repos, page...)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Len(t, repos, 3)
+	assert.Equal(t, "acme", repos[0].Owner)
+	assert.True(t, repos[1].Fork)
+	assert.True(t, repos[2].Archived)
+}
@@ -0,0 +1,34 @@
+package github
+
+import (
+	"strings"
+)
+
+const linkHeader = "Link"
+
+// nextPageURL extracts the rel="next" target from a GitHub Link header, e.g.
+//
+//	<https://api.github.com/repositories/1/commits?page=2>; rel="next", <...>; rel="last"
+//
+// It returns an empty string once the last page has been reached.
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		segments := strings.Split(part, ";")
+		if len(segments) < 2 {
+			continue
+		}
+
+		target := strings.TrimSpace(segments[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+
+		for _, param := range segments[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(target, "<>")
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,176 @@
+package github
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+// maxSearchKeywords is the longest keyword text the search API accepts.
+const maxSearchKeywords = 256
+
+var (
+	loginPattern = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9]|-[A-Za-z0-9]){0,38}$`)
+	topicPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+	searchSorts  = map[string]bool{"stars": true, "forks": true, "help-wanted-issues": true, "updated": true}
+	searchOrders = map[string]bool{"asc": true, "desc": true}
+)
+
+// QueryBuilder assembles a repository search query qualifier by qualifier.
+//
+//	q, err := github.NewSearchQuery("cryptocurrency").
+//		Language("go").
+//		StarsAbove(100).
+//		Archived(false).
+//		Sort("stars", "desc").
+//		Build()
+type QueryBuilder struct {
+	query object.SearchQuery
+}
+
+// NewSearchQuery starts a query matching keywords.
+func NewSearchQuery(keywords string) *QueryBuilder {
+	return &QueryBuilder{query: object.SearchQuery{Keywords: keywords}}
+}
+
+func (b *QueryBuilder) Language(language string) *QueryBuilder {
+	b.query.Language = language
+	return b
+}
+
+func (b *QueryBuilder) StarsAbove(n int) *QueryBuilder {
+	b.query.StarsAbove = n
+	return b
+}
+
+func (b *QueryBuilder) PushedAfter(t time.Time) *QueryBuilder {
+	b.query.PushedAfter = t
+	return b
+}
+
+// Topic adds a topic the repositories must carry; it can be called repeatedly.
+func (b *QueryBuilder) Topic(topic string) *QueryBuilder {
+	b.query.Topics = append(b.query.Topics, topic)
+	return b
+}
+
+func (b *QueryBuilder) Org(org string) *QueryBuilder {
+	b.query.Org = org
+	return b
+}
+
+func (b *QueryBuilder) Archived(archived bool) *QueryBuilder {
+	b.query.Archived = &archived
+	return b
+}
+
+func (b *QueryBuilder) Fork(fork object.ForkFilter) *QueryBuilder {
+	b.query.Fork = fork
+	return b
+}
+
+func (b *QueryBuilder) Sort(sort, order string) *QueryBuilder {
+	b.query.Sort = sort
+	b.query.Order = order
+	return b
+}
+
+// Build validates the query and returns it.
+func (b *QueryBuilder) Build() (object.SearchQuery, error) {
+	if err := ValidateSearchQuery(b.query); err != nil {
+		return object.SearchQuery{}, err
+	}
+	return b.query, nil
+}
+
+// ValidateSearchQuery reports the first qualifier of q GitHub would reject or
+// misread.
+func ValidateSearchQuery(q object.SearchQuery) error {
+	keywords := strings.TrimSpace(q.Keywords)
+	if keywords == "" && q.Language == "" && len(q.Topics) == 0 && q.Org == "" {
+		return fmt.Errorf("search query needs keywords or a language, topic or org qualifier")
+	}
+	if len(keywords) > maxSearchKeywords {
+		return fmt.Errorf("search keywords are longer than %d characters", maxSearchKeywords)
+	}
+	if strings.ContainsAny(q.Language, "\":") {
+		return fmt.Errorf("invalid search language %q", q.Language)
+	}
+	if q.StarsAbove < 0 {
+		return fmt.Errorf("invalid search star count %d", q.StarsAbove)
+	}
+	for _, topic := range q.Topics {
+		if !topicPattern.MatchString(topic) {
+			return fmt.Errorf("invalid search topic %q", topic)
+		}
+	}
+	if q.Org != "" && !loginPattern.MatchString(q.Org) {
+		return fmt.Errorf("invalid search org %q", q.Org)
+	}
+	switch q.Fork {
+	case object.ForksExcluded, object.ForksIncluded, object.ForksOnly:
+	default:
+		return fmt.Errorf("invalid search fork filter %q", q.Fork)
+	}
+	if q.Sort != "" && !searchSorts[q.Sort] {
+		return fmt.Errorf("invalid search sort %q", q.Sort)
+	}
+	if q.Order != "" && !searchOrders[q.Order] {
+		return fmt.Errorf("invalid search order %q", q.Order)
+	}
+	if q.Order != "" && q.Sort == "" {
+		return fmt.Errorf("search order %q needs a sort", q.Order)
+	}
+
+	return nil
+}
+
+// searchText renders the keywords and qualifiers of q as the text of a search
+// query. Sort and order are left to the caller, since the REST and GraphQL APIs
+// take them differently.
+func searchText(q object.SearchQuery) (string, error) {
+	if err := ValidateSearchQuery(q); err != nil {
+		return "", err
+	}
+
+	terms := make([]string, 0, 8)
+	if keywords := strings.TrimSpace(q.Keywords); keywords != "" {
+		terms = append(terms, keywords)
+	}
+	if q.Language != "" {
+		terms = append(terms, "language:"+qualifierValue(q.Language))
+	}
+	if q.StarsAbove > 0 {
+		terms = append(terms, "stars:>"+strconv.Itoa(q.StarsAbove))
+	}
+	if !q.PushedAfter.IsZero() {
+		terms = append(terms, "pushed:>"+q.PushedAfter.UTC().Format(searchDateLayout))
+	}
+	for _, topic := range q.Topics {
+		terms = append(terms, "topic:"+topic)
+	}
+	if q.Org != "" {
+		terms = append(terms, "org:"+q.Org)
+	}
+	if q.Archived != nil {
+		terms = append(terms, "archived:"+strconv.FormatBool(*q.Archived))
+	}
+	if q.Fork != object.ForksExcluded {
+		terms = append(terms, "fork:"+string(q.Fork))
+	}
+
+	return strings.Join(terms, " "), nil
+}
+
+// qualifierValue quotes values containing spaces, such as "Jupyter Notebook".
+func qualifierValue(value string) string {
+	if strings.ContainsAny(value, " \t") {
+		return `"` + value + `"`
+	}
+	return value
+}
@@ -0,0 +1,56 @@
+package github
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchQueryText(t *testing.T) {
+	q, err := NewSearchQuery("cryptocurrency wallet").
+		Language("Jupyter Notebook").
+		StarsAbove(100).
+		PushedAfter(time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)).
+		Topic("defi").
+		Org("acme-labs").
+		Archived(false).
+		Fork(object.ForksOnly).
+		Sort("stars", "desc").
+		Build()
+	require.NoError(t, err)
+
+	text, err := searchText(q)
+	require.NoError(t, err)
+	assert.Equal(t, `cryptocurrency wallet language:"Jupyter Notebook" stars:>100 pushed:>2024-03-01 topic:defi org:acme-labs archived:false fork:only`, text)
+
+	values := url.Values{"q": {text}}
+	assert.Equal(t, "q=cryptocurrency+wallet+language%3A%22Jupyter+Notebook%22+stars%3A%3E100+pushed%3A%3E2024-03-01+topic%3Adefi+org%3Aacme-labs+archived%3Afalse+fork%3Aonly", values.Encode())
+}
+
+func TestSearchQueryValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		build *QueryBuilder
+	}{
+		{name: "empty", build: NewSearchQuery("  ")},
+		{name: "negative stars", build: NewSearchQuery("go").StarsAbove(-1)},
+		{name: "topic with spaces", build: NewSearchQuery("go").Topic("block chain")},
+		{name: "org with slash", build: NewSearchQuery("go").Org("acme/labs")},
+		{name: "language with qualifier", build: NewSearchQuery("go").Language("go stars:>1")},
+		{name: "unknown fork filter", build: NewSearchQuery("go").Fork("false")},
+		{name: "unknown sort", build: NewSearchQuery("go").Sort("name", "asc")},
+		{name: "unknown order", build: NewSearchQuery("go").Sort("stars", "up")},
+		{name: "order without sort", build: NewSearchQuery("go").Sort("", "asc")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.build.Build()
+			assert.Error(t, err)
+		})
+	}
+}
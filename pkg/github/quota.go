@@ -0,0 +1,96 @@
+package github
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+const (
+	rateLimitingLimitHeader    = "X-RateLimit-Limit"
+	rateLimitingResourceHeader = "X-RateLimit-Resource"
+)
+
+type quotaKey struct {
+	credential string
+	resource   string
+}
+
+// quotaTracker remembers the rate limit GitHub last reported for each credential
+// and rate-limit resource, and paces requests to it through its limiter.
+type quotaTracker struct {
+	mu      sync.Mutex
+	limits  map[quotaKey]object.RateLimit
+	limiter *limiter
+}
+
+func newQuotaTracker() *quotaTracker {
+	return &quotaTracker{limits: make(map[quotaKey]object.RateLimit), limiter: newLimiter()}
+}
+
+// observe records the rate-limit headers of a response made with credential id.
+func (q *quotaTracker) observe(id string, header http.Header) {
+	remaining, err := strconv.Atoi(header.Get(rateLimitingRemainingHeader))
+	if err != nil {
+		return
+	}
+
+	limit, _ := strconv.Atoi(header.Get(rateLimitingLimitHeader))
+	reset, _ := strconv.ParseInt(header.Get(rateLimitingResetHeader), 10, 64)
+	resource := header.Get(rateLimitingResourceHeader)
+	if resource == "" {
+		resource = object.ResourceCore
+	}
+
+	q.record(object.RateLimit{
+		Credential: id,
+		Resource:   resource,
+		Limit:      limit,
+		Remaining:  remaining,
+		Reset:      time.Unix(reset, 0),
+	})
+}
+
+// record stores limit as the latest known state of its credential and resource.
+func (q *quotaTracker) record(limit object.RateLimit) {
+	q.mu.Lock()
+	q.limits[quotaKey{credential: limit.Credential, resource: limit.Resource}] = limit
+	q.mu.Unlock()
+
+	q.limiter.update(limit)
+}
+
+// remaining reports how many requests, or points for graphql, credential id has
+// left of resource. Credentials that have not used it yet, or whose window has
+// reset, report ok == false.
+func (q *quotaTracker) remaining(id, resource string) (n int, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	limit, found := q.limits[quotaKey{credential: id, resource: resource}]
+	if !found || time.Now().After(limit.Reset) {
+		return 0, false
+	}
+	return limit.Remaining, true
+}
+
+func (q *quotaTracker) snapshot() []object.RateLimit {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	limits := make([]object.RateLimit, 0, len(q.limits))
+	for _, limit := range q.limits {
+		limits = append(limits, limit)
+	}
+	sort.Slice(limits, func(i, j int) bool {
+		if limits[i].Credential != limits[j].Credential {
+			return limits[i].Credential < limits[j].Credential
+		}
+		return limits[i].Resource < limits[j].Resource
+	})
+	return limits
+}
@@ -2,144 +2,461 @@ package github
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/httpclient"
 	"github.com/project/pkg/object"
+	"log"
+	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 const (
 	rateLimitingRemainingHeader = "X-RateLimit-Remaining"
 	rateLimitingResetHeader     = "X-RateLimit-Reset"
+
+	// commitsPerPage is the largest page size GitHub accepts for the commits endpoint.
+	commitsPerPage = 100
+	// defaultBaseURL is the public API, used when no base URL is configured.
+	defaultBaseURL = "https://api.github.com"
+	// defaultMaxCommitPages bounds a single FetchCommits call to 10,000 commits
+	// unless GITHUB_MAX_COMMIT_PAGES says otherwise.
+	defaultMaxCommitPages = 100
 )
 
 type github struct {
+	// baseURL is the REST API root, https://api.github.com or the /api/v3 root
+	// of a GitHub Enterprise Server instance.
+	baseURL string
+	// client is shared by every request to the host, see WithHTTPClient.
+	client    *resty.Client
+	tlsConfig *tls.Config
+	// maxCommitPages caps how many pages FetchCommits follows; zero means no cap.
+	maxCommitPages int
+	cache          Cache
+	auth           authenticator
+	quota          *quotaTracker
+	maxConcurrency int
+	throttle       *throttle
+	tokens         []string
+	app            *AppConfig
+	useGraphQL     bool
+}
+
+// Option customises the client built by NewGithub.
+type Option func(*github)
+
+// WithApp authenticates as a GitHub App, using an installation access token of
+// the installation covering each repository owner. It takes precedence over tokens.
+func WithApp(cfg AppConfig) Option {
+	return func(g *github) {
+		g.app = &cfg
+	}
+}
+
+// WithBaseURL points the client at the REST API of baseURL instead of
+// GITHUB_BASE_URL, e.g. https://ghe.example/api/v3 for GitHub Enterprise Server.
+func WithBaseURL(baseURL string) Option {
+	return func(g *github) {
+		g.baseURL = baseURL
+	}
 }
 
-func NewGithub() object.GitDetails {
-	return github{}
+// WithTLSConfig sets the TLS settings used to reach the API, e.g. the CA of an
+// Enterprise Server instance with a private certificate. It has no effect on a
+// client given WithHTTPClient.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(g *github) {
+		g.tlsConfig = cfg
+	}
+}
+
+// WithHTTPClient sends every request through httpClient instead of a client
+// built from httpclient.ConfigFromEnv, e.g. to add middlewares.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(g *github) {
+		g.client = resty.NewWithClient(httpClient)
+	}
+}
+
+// WithMaxConcurrency caps the requests the client has in flight at once
+// instead of GITHUB_MAX_CONCURRENCY. The cap is lowered for a while after
+// repeated secondary rate limits.
+func WithMaxConcurrency(n int) Option {
+	return func(g *github) {
+		g.maxConcurrency = n
+	}
+}
+
+// WithCache makes the client send conditional requests using the validators in
+// cache and report unchanged resources as object.ErrNotModified.
+func WithCache(cache Cache) Option {
+	return func(g *github) {
+		g.cache = cache
+	}
+}
+
+// WithGraphQL serves GitDetails from the GraphQL v4 API instead of REST. The
+// returned provider also implements object.BatchFetcher.
+func WithGraphQL() Option {
+	return func(g *github) {
+		g.useGraphQL = true
+	}
+}
+
+// WithTokens authenticates with a pool of personal access tokens instead of the
+// GITHUB_TOKEN/GITHUB_TOKENS environment variables. Every request uses the token
+// with the most remaining quota.
+func WithTokens(tokens ...string) Option {
+	return func(g *github) {
+		g.tokens = tokens
+	}
+}
+
+// HostFromEnv names the host GITHUB_BASE_URL points at, see HostOf.
+func HostFromEnv() string {
+	return HostOf(os.Getenv("GITHUB_BASE_URL"))
+}
+
+// HostOf names the host of the API at baseURL: github.com for the public API,
+// the instance host for GitHub Enterprise Server.
+func HostOf(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" || strings.EqualFold(u.Host, "api.github.com") {
+		return "github.com"
+	}
+	return strings.ToLower(u.Host)
 }
 
-func (g github) SearchRepos(ctx context.Context, interest string) ([]object.Repository, int64, error) {
+// NewGithub returns a GitDetails reading from the GitHub API at GITHUB_BASE_URL,
+// or the host given WithBaseURL. Every client tracks the quota of its own
+// credentials, so clients of several hosts can be used side by side.
+func NewGithub(opts ...Option) object.GitDetails {
+	maxCommitPages := defaultMaxCommitPages
+	if v := os.Getenv("GITHUB_MAX_COMMIT_PAGES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Printf("invalid GITHUB_MAX_COMMIT_PAGES %q, using %d", v, defaultMaxCommitPages)
+		} else {
+			maxCommitPages = n
+		}
+	}
+
+	maxConcurrency := defaultMaxConcurrency
+	if v := os.Getenv("GITHUB_MAX_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			log.Printf("invalid GITHUB_MAX_CONCURRENCY %q, using %d", v, defaultMaxConcurrency)
+		} else {
+			maxConcurrency = n
+		}
+	}
+
+	g := github{
+		baseURL:        os.Getenv("GITHUB_BASE_URL"),
+		maxCommitPages: maxCommitPages,
+		maxConcurrency: maxConcurrency,
+		quota:          newQuotaTracker(),
+		tokens:         tokensFromEnv(),
+		useGraphQL:     strings.EqualFold(os.Getenv("GITHUB_API"), "graphql"),
+	}
+	for _, opt := range opts {
+		opt(&g)
+	}
+	if g.baseURL == "" {
+		g.baseURL = defaultBaseURL
+	}
+	g.baseURL = strings.TrimRight(g.baseURL, "/")
+	g.throttle = newThrottle(HostOf(g.baseURL), g.maxConcurrency)
+	if g.client == nil {
+		cfg := httpclient.ConfigFromEnv()
+		cfg.TLS = g.tlsConfig
+		g.client = resty.NewWithClient(httpclient.New(cfg))
+	}
+
+	if g.app != nil {
+		app := *g.app
+		if app.BaseURL == "" {
+			app.BaseURL = g.baseURL
+		}
+		g.auth = newAppAuth(app, g.client)
+	} else {
+		g.auth = newTokenPool(g.tokens, g.quota)
+	}
+
+	if g.useGraphQL {
+		return graphQL{github: g}
+	}
+	return g
+}
+
+// RateLimits reports the quota GitHub last returned for every credential in use.
+func (g github) RateLimits() []object.RateLimit {
+	return g.quota.snapshot()
+}
+
+// Budget reports what is left of resource, one of the object.Resource names,
+// across every credential in use and the pace requests are let through at.
+func (g github) Budget(resource string) (object.Budget, bool) {
+	return g.quota.limiter.budget(resource)
+}
+
+// get issues a GET for url on behalf of owner, see send.
+func (g github) get(ctx context.Context, owner, url string, conditional bool) (*resty.Response, error) {
+	resp, _, err := g.send(ctx, resty.MethodGet, owner, url, nil, conditional)
+	return resp, err
+}
+
+// send issues a request for url on behalf of owner, authenticating with the
+// credential with the most quota left for the rate-limit resource of url and
+// recording the quota GitHub reports for it. When that credential is rejected
+// as exhausted the request is repeated with the next one, until every
+// credential has been tried. Conditional requests revalidate against
+// the cache. Requests go through the throttle of the client, which refuses them
+// while it is paused by a secondary rate limit. It returns the id of the
+// credential used for the final attempt.
+func (g github) send(ctx context.Context, method, owner, url string, body interface{}, conditional bool) (*resty.Response, string, error) {
 	var (
-		response Repositories
-		result   []object.Repository
+		resp   *resty.Response
+		lastID string
 	)
+	tried := make(map[string]bool)
+	resource := resourceOf(url)
+	for {
+		cred, err := g.auth.credential(ctx, owner, resource, tried)
+		if err != nil {
+			return nil, "", err
+		}
+		if tried[cred.id] {
+			return resp, lastID, nil
+		}
+		tried[cred.id] = true
+		lastID = cred.id
 
-	client := resty.New()
-	resp, err := client.R().
-		SetContext(ctx).
-		Get(fmt.Sprintf("%s/search/repositories?q=%s", os.Getenv("GITHUB_BASE_URL"), interest))
-	if err != nil {
-		return nil, 0, err
-	}
+		if err := object.Admit(ctx, resource); err != nil {
+			return nil, "", err
+		}
+		if err := g.quota.limiter.wait(ctx, cred.id, resource); err != nil {
+			return nil, "", err
+		}
 
-	if err := json.Unmarshal(resp.Body(), &response); err != nil {
-		return nil, 0, err
-	}
-
-	for _, rr := range response.Items {
-		result = append(result, object.Repository{
-			Name:            rr.Name,
-			Owner:           rr.Owner.Login,
-			Description:     rr.Description,
-			URL:             rr.Url,
-			Language:        rr.Language,
-			ForksCount:      rr.ForksCount,
-			StarsCount:      rr.StargazersCount,
-			OpenIssuesCount: rr.OpenIssuesCount,
-			WatchersCount:   rr.WatchersCount,
-		})
-	}
-
-	rateLimitReset := resp.Header().Get(rateLimitingResetHeader)
-	rateLimitRemaining := resp.Header().Get(rateLimitingRemainingHeader)
-	if rateLimitRemaining == "0" {
-		resetTime, err := strconv.ParseInt(rateLimitReset, 10, 64)
+		req := g.client.R().SetContext(ctx)
+		if cred.authorization != "" {
+			req.SetHeader("Authorization", cred.authorization)
+		}
+		if conditional {
+			g.revalidate(ctx, req, url)
+		}
+		if body != nil {
+			req.SetBody(body)
+		}
+
+		release, err := g.throttle.acquire(ctx)
 		if err != nil {
-			return nil, 0, err
+			return nil, "", err
+		}
+		resp, err = req.Execute(method, url)
+		release()
+		if err != nil {
+			return nil, "", err
 		}
 
-		return nil, resetTime, errors.New("rate_limit")
+		g.throttle.observe(resp)
+		g.quota.observe(cred.id, resp.Header())
+		if !isPrimaryRateLimit(resp) {
+			return resp, cred.id, nil
+		}
 	}
+}
 
-	return result, 0, nil
+// ValidateSearchQuery reports whether the search API accepts query.
+func (g github) ValidateSearchQuery(query object.SearchQuery) error {
+	return ValidateSearchQuery(query)
 }
 
-func (github) FetchRepo(ctx context.Context, owner, repo string) (*object.Repository, int64, error) {
-	client := resty.New()
-	resp, err := client.R().
-		SetContext(ctx).
-		Get(fmt.Sprintf("%s/repos/%s/%s", os.Getenv("GITHUB_BASE_URL"), owner, repo))
+// SearchRepos hands every repository matching query to handle, following the
+// pagination of the search API and slicing searches above its result cap.
+func (g github) SearchRepos(ctx context.Context, query object.SearchQuery, handle object.RepoPageFunc) error {
+	text, err := searchText(query)
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
 
-	var repository object.Repository
-	if err := json.Unmarshal(resp.Body(), &repository); err != nil {
-		return nil, 0, err
+	searchURL := func(q string) string {
+		params := url.Values{}
+		params.Set("q", q)
+		params.Set("per_page", strconv.Itoa(searchPerPage))
+		if query.Sort != "" {
+			params.Set("sort", query.Sort)
+		}
+		if query.Order != "" {
+			params.Set("order", query.Order)
+		}
+		return fmt.Sprintf("%s/search/repositories?%s", g.baseURL, params.Encode())
 	}
+	firstURL := searchURL(text)
+
+	// validators are only kept for searches answered by a single page, where an
+	// unchanged first page means nothing changed at all
+	var single *resty.Response
+	fetch := func(ctx context.Context, q, cursor string) (searchPage, error) {
+		pageURL := cursor
+		if pageURL == "" {
+			pageURL = searchURL(q)
+		}
 
-	rateLimitReset := resp.Header().Get(rateLimitingResetHeader)
-	rateLimitRemaining := resp.Header().Get(rateLimitingRemainingHeader)
-	if rateLimitRemaining == "0" {
-		resetTime, err := strconv.ParseInt(rateLimitReset, 10, 64)
+		resp, err := g.get(ctx, "", pageURL, pageURL == firstURL)
 		if err != nil {
-			return nil, 0, err
+			return searchPage{}, err
 		}
 
-		return nil, resetTime, errors.New("rate_limit")
+		if resp.StatusCode() == http.StatusNotModified {
+			return searchPage{}, object.ErrNotModified
+		}
+		if err := checkResponse(resp, "search results"); err != nil {
+			return searchPage{}, err
+		}
+
+		var response Repositories
+		if err := json.Unmarshal(resp.Body(), &response); err != nil {
+			return searchPage{}, err
+		}
+
+		page := searchPage{
+			repos:      make([]object.Repository, 0, len(response.Items)),
+			total:      response.TotalCount,
+			incomplete: response.IncompleteResults,
+			next:       nextPageURL(resp.Header().Get(linkHeader)),
+		}
+		for _, rr := range response.Items {
+			page.repos = append(page.repos, rr.toObject())
+		}
+
+		if pageURL == firstURL && page.next == "" && !page.incomplete {
+			single = resp
+		}
+		return page, nil
+	}
+
+	if err := searchAll(ctx, text, fetch, handle); err != nil {
+		return err
 	}
-	return &repository, 0, nil
+
+	g.remember(ctx, firstURL, single)
+	return nil
 }
 
-func (github) FetchCommits(ctx context.Context, owner, repo string) ([]object.Commit, int64, error) {
-	client := resty.New()
-	resp, err := client.R().
-		SetContext(ctx).
-		Get(fmt.Sprintf("%s/repos/%s/%s/commits", os.Getenv("GITHUB_BASE_URL"), owner, repo))
+func (g github) FetchRepo(ctx context.Context, owner, repo string) (*object.Repository, error) {
+	repoURL := fmt.Sprintf("%s/repos/%s/%s", g.baseURL, owner, repo)
+
+	resp, err := g.get(ctx, owner, repoURL, true)
 	if err != nil {
-		return nil, 0, err
-	}
-
-	var commits []struct {
-		SHA    string `json:"sha"`
-		Commit struct {
-			Author struct {
-				Name  string    `json:"name"`
-				Email string    `json:"email"`
-				Date  time.Time `json:"date"`
-			} `json:"author"`
-			Message string `json:"message"`
-		} `json:"commit"`
-	}
-	if err := json.Unmarshal(resp.Body(), &commits); err != nil {
-		return nil, 0, err
-	}
-
-	var commitList []object.Commit
-	for _, c := range commits {
-		commitList = append(commitList, object.Commit{
-			SHA:         c.SHA,
-			AuthorName:  c.Commit.Author.Name,
-			AuthorEmail: c.Commit.Author.Email,
-			Message:     c.Commit.Message,
-			Date:        c.Commit.Author.Date,
-		})
-	}
-
-	rateLimitReset := resp.Header().Get(rateLimitingResetHeader)
-	rateLimitRemaining := resp.Header().Get(rateLimitingRemainingHeader)
-	if rateLimitRemaining == "0" {
-		resetTime, err := strconv.ParseInt(rateLimitReset, 10, 64)
+		return nil, err
+	}
+
+	if resp.StatusCode() == http.StatusNotModified {
+		return nil, object.ErrNotModified
+	}
+	if err := checkResponse(resp, fmt.Sprintf("repository %s/%s", owner, repo)); err != nil {
+		return nil, err
+	}
+
+	var response Repository
+	if err := json.Unmarshal(resp.Body(), &response); err != nil {
+		return nil, err
+	}
+
+	g.remember(ctx, repoURL, resp)
+	repository := response.toObject()
+	return &repository, nil
+}
+
+func (g github) FetchCommits(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error {
+	query := url.Values{}
+	query.Set("per_page", strconv.Itoa(commitsPerPage))
+	if !opts.Since.IsZero() {
+		query.Set("since", opts.Since.UTC().Format(time.RFC3339))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", opts.Until.UTC().Format(time.RFC3339))
+	}
+	firstURL := fmt.Sprintf("%s/repos/%s/%s/commits?%s", g.baseURL, owner, repo, query.Encode())
+
+	// only the first page is requested conditionally: once it is unchanged there
+	// is nothing new further down the history either
+	var firstPage *resty.Response
+	pageURL := firstURL
+	for page := 1; pageURL != ""; page++ {
+		if g.maxCommitPages > 0 && page > g.maxCommitPages {
+			// the history was not read to the end, so it must not be
+			// revalidated as unchanged next time
+			return object.ErrTruncated
+		}
+
+		resp, err := g.get(ctx, owner, pageURL, page == 1)
 		if err != nil {
-			return nil, 0, err
+			return err
 		}
-		return nil, resetTime, errors.New("rate_limit")
+
+		if resp.StatusCode() == http.StatusNotModified {
+			return object.ErrNotModified
+		}
+		if resp.StatusCode() == http.StatusConflict {
+			// GitHub answers 409 for repositories without any commits
+			break
+		}
+		if err := checkResponse(resp, fmt.Sprintf("repository %s/%s", owner, repo)); err != nil {
+			return err
+		}
+		if page == 1 {
+			firstPage = resp
+		}
+
+		var commits []struct {
+			SHA    string `json:"sha"`
+			Commit struct {
+				Author struct {
+					Name  string    `json:"name"`
+					Email string    `json:"email"`
+					Date  time.Time `json:"date"`
+				} `json:"author"`
+				Committer struct {
+					Date time.Time `json:"date"`
+				} `json:"committer"`
+				Message string `json:"message"`
+			} `json:"commit"`
+		}
+		if err := json.Unmarshal(resp.Body(), &commits); err != nil {
+			return err
+		}
+
+		commitList := make([]object.Commit, 0, len(commits))
+		for _, c := range commits {
+			commitList = append(commitList, object.Commit{
+				SHA:         c.SHA,
+				AuthorName:  c.Commit.Author.Name,
+				AuthorEmail: c.Commit.Author.Email,
+				Message:     c.Commit.Message,
+				Date:        c.Commit.Author.Date,
+				CommittedAt: c.Commit.Committer.Date,
+			})
+		}
+
+		if len(commitList) > 0 {
+			if err := handle(commitList); err != nil {
+				return err
+			}
+		}
+
+		pageURL = nextPageURL(resp.Header().Get(linkHeader))
 	}
-	return commitList, 0, nil
+
+	g.remember(ctx, firstURL, firstPage)
+	return nil
 }
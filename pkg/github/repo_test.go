@@ -0,0 +1,38 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchCommitsReportsTruncatedHistory(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/acme/api/commits", r.URL.Path)
+		w.Header().Set(linkHeader, fmt.Sprintf(`<%s/repos/acme/api/commits?page=2>; rel="next"`, srv.URL))
+		_, _ = w.Write([]byte(`[{"sha": "c1", "commit": {
+			"author": {"name": "ada", "date": "2024-06-01T00:00:00Z"},
+			"committer": {"name": "bob", "date": "2024-07-01T00:00:00Z"}}}]`))
+	}))
+	defer srv.Close()
+	t.Setenv("GITHUB_MAX_COMMIT_PAGES", "1")
+
+	var commits []object.Commit
+	err := NewGithub(WithBaseURL(srv.URL), WithTokens()).FetchCommits(context.Background(), "acme", "api", object.CommitOptions{}, func(page []object.Commit) error {
+		commits = append(commits, page...)
+		return nil
+	})
+	assert.ErrorIs(t, err, object.ErrTruncated)
+
+	require.Len(t, commits, 1)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), commits[0].Date)
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), commits[0].CommittedAt)
+}
@@ -0,0 +1,131 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+const (
+	// searchPerPage is the largest page size the search API accepts.
+	searchPerPage = 100
+	// searchResultCap is the number of results GitHub serves for any one search
+	// query; larger result sets have to be split into narrower queries.
+	searchResultCap = 1000
+	// searchIncompleteRetries bounds how often a page reported as
+	// incomplete_results is requested again before it is accepted as is.
+	searchIncompleteRetries = 3
+	searchDateLayout        = "2006-01-02"
+)
+
+// searchEpoch predates every repository on GitHub.
+var searchEpoch = time.Date(2007, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+// searchPage is one page of search results. next is the opaque cursor of the
+// following page, empty on the last one.
+type searchPage struct {
+	repos      []object.Repository
+	total      int
+	incomplete bool
+	next       string
+}
+
+// searchPageFunc fetches the page of query that cursor points at; an empty
+// cursor asks for the first page.
+type searchPageFunc func(ctx context.Context, query, cursor string) (searchPage, error)
+
+// searchAll hands every repository matching query to handle. Result sets above
+// searchResultCap are split into created-date slices, bisected until each slice
+// fits under the cap.
+func searchAll(ctx context.Context, query string, fetch searchPageFunc, handle object.RepoPageFunc) error {
+	first, err := fetchSearchPage(ctx, fetch, query, "")
+	if err != nil {
+		return err
+	}
+
+	if first.total <= searchResultCap {
+		return paginateSearch(ctx, fetch, query, first, handle)
+	}
+
+	return searchSlice(ctx, query, searchEpoch, time.Now().UTC().Truncate(24*time.Hour), fetch, handle)
+}
+
+// searchSlice searches the repositories of query created between from and to,
+// both inclusive.
+func searchSlice(ctx context.Context, query string, from, to time.Time, fetch searchPageFunc, handle object.RepoPageFunc) error {
+	sliced := fmt.Sprintf("%s created:%s..%s", query, from.Format(searchDateLayout), to.Format(searchDateLayout))
+	first, err := fetchSearchPage(ctx, fetch, sliced, "")
+	if err != nil {
+		return err
+	}
+
+	if first.total > searchResultCap {
+		days := int(to.Sub(from).Hours() / 24)
+		if days > 0 {
+			mid := from.AddDate(0, 0, days/2)
+			if err := searchSlice(ctx, query, from, mid, fetch, handle); err != nil {
+				return err
+			}
+			return searchSlice(ctx, query, mid.AddDate(0, 0, 1), to, fetch, handle)
+		}
+
+		log.Printf("search %q matches %d repositories created on %s, only the first %d are collected",
+			query, first.total, from.Format(searchDateLayout), searchResultCap)
+	}
+
+	return paginateSearch(ctx, fetch, sliced, first, handle)
+}
+
+// paginateSearch hands first and every following page of query to handle.
+func paginateSearch(ctx context.Context, fetch searchPageFunc, query string, first searchPage, handle object.RepoPageFunc) error {
+	page := first
+	for {
+		if len(page.repos) > 0 {
+			if err := handle(page.repos); err != nil {
+				return err
+			}
+		}
+
+		if page.next == "" {
+			return nil
+		}
+
+		var err error
+		if page, err = fetchSearchPage(ctx, fetch, query, page.next); err != nil {
+			return err
+		}
+	}
+}
+
+// fetchSearchPage fetches a page, waiting out rate limits through the waiter of
+// ctx and asking again when GitHub reports the results as incomplete.
+func fetchSearchPage(ctx context.Context, fetch searchPageFunc, query, cursor string) (searchPage, error) {
+	for attempt := 1; ; {
+		page, err := fetch(ctx, query, cursor)
+		if err != nil {
+			retry, waitErr := object.Wait(ctx, err)
+			if waitErr != nil {
+				return searchPage{}, waitErr
+			}
+			if retry {
+				continue
+			}
+			return searchPage{}, err
+		}
+
+		if !page.incomplete || attempt >= searchIncompleteRetries {
+			return page, nil
+		}
+
+		// GitHub timed out assembling the page, give it a moment before asking again
+		select {
+		case <-time.After(time.Duration(attempt) * time.Second):
+		case <-ctx.Done():
+			return searchPage{}, ctx.Err()
+		}
+		attempt++
+	}
+}
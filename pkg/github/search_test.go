@@ -0,0 +1,61 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchAllSlicesLargeResultSets(t *testing.T) {
+	created := regexp.MustCompile(`created:(\d{4}-\d{2}-\d{2})\.\.(\d{4}-\d{2}-\d{2})`)
+	// one matching repository per day since 2020, far more than a single query serves
+	perDay := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	count := func(query string) int {
+		m := created.FindStringSubmatch(query)
+		if m == nil {
+			return int(time.Since(perDay).Hours()/24) + 1
+		}
+		from, _ := time.Parse(searchDateLayout, m[1])
+		to, _ := time.Parse(searchDateLayout, m[2])
+		if from.Before(perDay) {
+			from = perDay
+		}
+		if to.Before(from) {
+			return 0
+		}
+		return int(to.Sub(from).Hours()/24) + 1
+	}
+
+	incompleteOnce := true
+	fetch := func(ctx context.Context, query, cursor string) (searchPage, error) {
+		total := count(query)
+		offset := 0
+		if cursor != "" {
+			_, _ = fmt.Sscanf(cursor, "%d", &offset)
+		}
+
+		page := searchPage{total: total, incomplete: incompleteOnce}
+		incompleteOnce = false
+		for i := offset; i < total && i < offset+searchPerPage && i < searchResultCap; i++ {
+			page.repos = append(page.repos, object.Repository{Name: fmt.Sprintf("%s#%d", query, i)})
+		}
+		if next := offset + searchPerPage; next < total && next < searchResultCap {
+			page.next = fmt.Sprint(next)
+		}
+		return page, nil
+	}
+
+	seen := 0
+	err := searchAll(context.Background(), "crypto", fetch, func(repos []object.Repository) error {
+		seen += len(repos)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, count("crypto"), seen)
+}
@@ -0,0 +1,145 @@
+package github
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/object"
+)
+
+const (
+	// defaultMaxConcurrency is how many requests a client has in flight at most
+	// unless GITHUB_MAX_CONCURRENCY says otherwise.
+	defaultMaxConcurrency = 4
+	// hitWindow is how close together secondary rate limits have to be for the
+	// concurrency to be cut: a single hit only pauses.
+	hitWindow = 10 * time.Minute
+	// recoverAfter is the number of successive unthrottled responses after which
+	// the concurrency grows by one again.
+	recoverAfter = 100
+)
+
+// throttle shields GitHub from bursts. It caps the requests in flight, halving
+// the cap when secondary rate limits follow each other and growing it back
+// slowly while none occur, and pauses every request of the client after each
+// secondary rate limit for as long as GitHub asked.
+type throttle struct {
+	host string
+	max  int
+	now  func() time.Time
+
+	mu          sync.Mutex
+	limit       int
+	inFlight    int
+	released    chan struct{}
+	pausedUntil time.Time
+	lastHit     time.Time
+	hits        int
+	successes   int
+}
+
+func newThrottle(host string, max int) *throttle {
+	if max < 1 {
+		max = 1
+	}
+	return &throttle{host: host, max: max, limit: max, now: time.Now, released: make(chan struct{})}
+}
+
+// acquire waits for a free slot and returns the function that gives it back.
+// While the client is paused it fails straight away with the remaining pause
+// as a SecondaryRateLimitError, which callers wait out like any other.
+func (t *throttle) acquire(ctx context.Context) (release func(), err error) {
+	if t == nil {
+		return func() {}, nil
+	}
+
+	for {
+		t.mu.Lock()
+		if wait := t.pausedUntil.Sub(t.now()); wait > 0 {
+			t.mu.Unlock()
+			return nil, &object.SecondaryRateLimitError{RetryAfter: wait}
+		}
+		if t.inFlight < t.limit {
+			t.inFlight++
+			t.mu.Unlock()
+			return t.release, nil
+		}
+		released := t.released
+		t.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (t *throttle) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.inFlight--
+	// wake every waiter, the ones that do not get the slot wait again
+	close(t.released)
+	t.released = make(chan struct{})
+}
+
+// observe pauses the client when resp is a secondary rate limit, and otherwise
+// counts towards growing the concurrency back.
+func (t *throttle) observe(resp *resty.Response) {
+	if t == nil {
+		return
+	}
+
+	retryAfter, limited := isSecondaryRateLimit(resp)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if !limited {
+		t.successes++
+		if t.successes >= recoverAfter && t.limit < t.max {
+			t.limit++
+			t.successes = 0
+			log.Printf("github %s: no secondary rate limits for %d requests, concurrency back up to %d", t.host, recoverAfter, t.limit)
+		}
+		return
+	}
+
+	t.successes = 0
+	if now.Sub(t.lastHit) < hitWindow {
+		t.hits++
+	} else {
+		t.hits = 1
+	}
+	t.lastHit = now
+	if t.hits > 1 && t.limit > 1 {
+		t.limit /= 2
+	}
+	if until := now.Add(retryAfter); until.After(t.pausedUntil) {
+		t.pausedUntil = until
+	}
+
+	log.Printf("github %s: secondary rate limit (%d in a row), pausing all requests for %s at a concurrency of %d",
+		t.host, t.hits, retryAfter.Round(time.Second), t.limit)
+}
+
+// isSecondaryRateLimit reports whether resp is GitHub throttling a burst while
+// primary quota is left, and how long it asked to back off.
+func isSecondaryRateLimit(resp *resty.Response) (time.Duration, bool) {
+	switch resp.StatusCode() {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+	default:
+		return 0, false
+	}
+	if isPrimaryRateLimit(resp) {
+		return 0, false
+	}
+	return secondaryRateLimit(resp)
+}
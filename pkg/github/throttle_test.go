@@ -0,0 +1,101 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func secondaryLimitResponse(t *testing.T) *resty.Response {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(retryAfterHeader, "30")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+	}))
+	defer srv.Close()
+
+	resp, err := resty.New().R().Get(srv.URL)
+	require.NoError(t, err)
+	return resp
+}
+
+func TestSecondaryRateLimitPausesEveryRequest(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set(retryAfterHeader, "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+	}))
+	defer srv.Close()
+
+	details := NewGithub(WithBaseURL(srv.URL), WithTokens("ghp_token"))
+
+	_, err := details.FetchRepo(context.Background(), "acme", "api")
+	var secondary *object.SecondaryRateLimitError
+	require.ErrorAs(t, err, &secondary)
+	assert.Equal(t, time.Minute, secondary.RetryAfter)
+
+	// the pause is kept locally, GitHub is not asked again until it is over
+	_, err = details.FetchRepo(context.Background(), "acme", "web")
+	require.ErrorAs(t, err, &secondary)
+	assert.LessOrEqual(t, secondary.RetryAfter, time.Minute)
+	assert.Equal(t, 1, requests)
+}
+
+func TestThrottleAdaptsConcurrency(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	th := newThrottle("github.com", 4)
+	th.now = func() time.Time { return now }
+	limited := secondaryLimitResponse(t)
+
+	// a single hit only pauses
+	th.observe(limited)
+	assert.Equal(t, 4, th.limit)
+	assert.Equal(t, now.Add(30*time.Second), th.pausedUntil)
+
+	now = now.Add(time.Minute)
+	th.observe(limited)
+	assert.Equal(t, 2, th.limit)
+
+	ok := &resty.Response{RawResponse: &http.Response{StatusCode: http.StatusOK}}
+	for i := 0; i < recoverAfter; i++ {
+		th.observe(ok)
+	}
+	assert.Equal(t, 3, th.limit)
+}
+
+func TestThrottleCapsRequestsInFlight(t *testing.T) {
+	th := newThrottle("github.com", 1)
+
+	release, err := th.acquire(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = th.acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	acquired := make(chan struct{})
+	go func() {
+		next, err := th.acquire(context.Background())
+		if assert.NoError(t, err) {
+			next()
+		}
+		close(acquired)
+	}()
+	release()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("waiting request did not get the released slot")
+	}
+}
@@ -0,0 +1,51 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/httpclient"
+	"github.com/project/pkg/object"
+)
+
+const (
+	rateLimitLimitHeader     = "RateLimit-Limit"
+	rateLimitRemainingHeader = "RateLimit-Remaining"
+	rateLimitResetHeader     = "RateLimit-Reset"
+)
+
+// checkResponse turns an unsuccessful response into one of the typed errors of
+// package object, mapping GitLab's RateLimit-* headers onto the same reset
+// semantics as GitHub's. resource names what was requested, for NotFoundError.
+func checkResponse(resp *resty.Response, resource string) error {
+	if resp.StatusCode() == http.StatusTooManyRequests && resp.Header().Get(rateLimitRemainingHeader) == "0" {
+		return &object.RateLimitError{Reset: resetTime(resp.Header())}
+	}
+	return httpclient.CheckResponse(resp, resource, errorMessage)
+}
+
+// resetTime reads RateLimit-Reset, falling back to Retry-After.
+func resetTime(header http.Header) time.Time {
+	if reset, err := strconv.ParseInt(header.Get(rateLimitResetHeader), 10, 64); err == nil {
+		return time.Unix(reset, 0)
+	}
+	return time.Now().Add(httpclient.RetryAfter(header))
+}
+
+// errorMessage extracts the message or error field of a GitLab error body.
+func errorMessage(body []byte) string {
+	var e struct {
+		Message interface{} `json:"message"`
+		Error   string      `json:"error"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil {
+		return ""
+	}
+	if message, ok := e.Message.(string); ok && message != "" {
+		return message
+	}
+	return e.Error
+}
@@ -0,0 +1,240 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/httpclient"
+	"github.com/project/pkg/object"
+)
+
+const (
+	// perPage is the largest page size GitLab accepts.
+	perPage = 100
+	// maxCommitPages bounds a single FetchCommits call to 10,000 commits.
+	maxCommitPages = 100
+
+	tokenHeader    = "PRIVATE-TOKEN"
+	nextPageHeader = "X-Next-Page"
+)
+
+type gitlab struct {
+	baseURL string
+	client  *resty.Client
+	token   string
+
+	mu    sync.Mutex
+	quota *object.RateLimit
+}
+
+// NewGitlab returns a GitDetails reading projects from a GitLab instance. Groups
+// and users take the place of GitHub's organizations and users, and a project's
+// full namespace path is its owner. The v4 API at GITLAB_BASE_URL, e.g.
+// https://gitlab.example.com/api/v4, is read with the personal, group or
+// project access token in GITLAB_TOKEN unless opts say otherwise.
+func NewGitlab(opts ...httpclient.Option) object.GitDetails {
+	o, client := httpclient.Options{
+		BaseURL: os.Getenv("GITLAB_BASE_URL"),
+		Token:   os.Getenv("GITLAB_TOKEN"),
+	}.Apply(opts...)
+
+	return &gitlab{baseURL: o.BaseURL, client: client, token: o.Token}
+}
+
+// HostFromEnv names the host GITLAB_BASE_URL points at, empty when it is unset.
+func HostFromEnv() string {
+	return httpclient.Host(os.Getenv("GITLAB_BASE_URL"), "")
+}
+
+// RateLimits reports the quota GitLab last returned for the token in use.
+func (g *gitlab) RateLimits() []object.RateLimit {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.quota == nil {
+		return nil
+	}
+	return []object.RateLimit{*g.quota}
+}
+
+func (g *gitlab) FetchRepo(ctx context.Context, owner, repo string) (*object.Repository, error) {
+	resource := fmt.Sprintf("project %s/%s", owner, repo)
+	projectURL := fmt.Sprintf("%s/projects/%s", g.baseURL, projectID(owner, repo))
+
+	resp, err := g.fetch(ctx, projectURL, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var p project
+	if err := json.Unmarshal(resp.Body(), &p); err != nil {
+		return nil, err
+	}
+	repository := p.toObject()
+
+	// projects do not carry a language, it is the largest share of the breakdown
+	resp, err = g.fetch(ctx, projectURL+"/languages", resource)
+	if err != nil {
+		return nil, err
+	}
+	var languages map[string]float64
+	if err := json.Unmarshal(resp.Body(), &languages); err != nil {
+		return nil, err
+	}
+	repository.Language = mainLanguage(languages)
+
+	return &repository, nil
+}
+
+func (g *gitlab) FetchCommits(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error {
+	query := url.Values{}
+	query.Set("per_page", strconv.Itoa(perPage))
+	if !opts.Since.IsZero() {
+		query.Set("since", opts.Since.UTC().Format(time.RFC3339))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", opts.Until.UTC().Format(time.RFC3339))
+	}
+	commitsURL := fmt.Sprintf("%s/projects/%s/repository/commits?%s", g.baseURL, projectID(owner, repo), query.Encode())
+
+	return g.paginate(ctx, commitsURL, fmt.Sprintf("project %s/%s", owner, repo), maxCommitPages, func(body []byte) error {
+		var commits []commit
+		if err := json.Unmarshal(body, &commits); err != nil {
+			return err
+		}
+
+		commitList := make([]object.Commit, 0, len(commits))
+		for _, c := range commits {
+			commitList = append(commitList, c.toObject())
+		}
+		if len(commitList) == 0 {
+			return nil
+		}
+		return handle(commitList)
+	})
+}
+
+// ListOrgRepos hands every project of the group org, subgroups included, to handle.
+func (g *gitlab) ListOrgRepos(ctx context.Context, org string, handle object.RepoPageFunc) error {
+	query := url.Values{}
+	query.Set("include_subgroups", "true")
+	query.Set("per_page", strconv.Itoa(perPage))
+	listURL := fmt.Sprintf("%s/groups/%s/projects?%s", g.baseURL, url.PathEscape(org), query.Encode())
+
+	return g.paginate(ctx, listURL, fmt.Sprintf("group %s", org), 0, projectPages(nil, handle))
+}
+
+// ListUserRepos hands every project of the personal namespace of user to handle.
+func (g *gitlab) ListUserRepos(ctx context.Context, user string, handle object.RepoPageFunc) error {
+	query := url.Values{}
+	query.Set("per_page", strconv.Itoa(perPage))
+	listURL := fmt.Sprintf("%s/users/%s/projects?%s", g.baseURL, url.PathEscape(user), query.Encode())
+
+	return g.paginate(ctx, listURL, fmt.Sprintf("user %s", user), 0, projectPages(nil, handle))
+}
+
+// paginate follows the X-Next-Page header from firstURL, handing every page body
+// to handle, up to maxPages pages when it is positive, see httpclient.Paginate.
+func (g *gitlab) paginate(ctx context.Context, firstURL, resource string, maxPages int, handle func(body []byte) error) error {
+	get := func(ctx context.Context, pageURL string) (*resty.Response, error) {
+		return g.fetch(ctx, pageURL, resource)
+	}
+
+	return httpclient.Paginate(ctx, firstURL, maxPages, get, func(resp *resty.Response) (string, error) {
+		if err := handle(resp.Body()); err != nil {
+			return "", err
+		}
+		return nextPage(resp.Request.URL, resp.Header().Get(nextPageHeader)), nil
+	})
+}
+
+// fetch issues a GET and turns an unsuccessful response for resource into an error.
+func (g *gitlab) fetch(ctx context.Context, url, resource string) (*resty.Response, error) {
+	resp, err := g.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp, resource); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// get issues an authenticated GET and records the quota GitLab reports.
+func (g *gitlab) get(ctx context.Context, url string) (*resty.Response, error) {
+	req := g.client.R().SetContext(ctx)
+	if g.token != "" {
+		req.SetHeader(tokenHeader, g.token)
+	}
+
+	resp, err := req.Get(url)
+	if err != nil {
+		return nil, err
+	}
+
+	g.observe(resp.Header())
+	return resp, nil
+}
+
+// observe records the RateLimit-* headers of a response.
+func (g *gitlab) observe(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get(rateLimitRemainingHeader))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(header.Get(rateLimitLimitHeader))
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.quota = &object.RateLimit{
+		Credential: "token",
+		Resource:   "api",
+		Limit:      limit,
+		Remaining:  remaining,
+		Reset:      resetTime(header),
+	}
+}
+
+// projectID is the URL-encoded full path GitLab accepts in place of a numeric
+// project id.
+func projectID(owner, repo string) string {
+	return url.PathEscape(owner + "/" + repo)
+}
+
+// nextPage replaces the page parameter of current with next, or returns an
+// empty string when GitLab reports no further page.
+func nextPage(current, next string) string {
+	if next == "" {
+		return ""
+	}
+
+	u, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	query := u.Query()
+	query.Set("page", next)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func mainLanguage(languages map[string]float64) string {
+	var (
+		language string
+		share    float64
+	)
+	for name, s := range languages {
+		if s > share || (s == share && name < language) {
+			language, share = name, s
+		}
+	}
+	return language
+}
@@ -0,0 +1,93 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/project/pkg/httpclient"
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchRepoUsesEncodedProjectPath(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get(tokenHeader))
+
+		switch r.URL.EscapedPath() {
+		case "/projects/acme%2Fplatform%2Fapi":
+			_, _ = w.Write([]byte(`{"path": "api", "namespace": {"full_path": "acme/platform"}, "star_count": 7,
+				"forked_from_project": {"id": 1}, "last_activity_at": "2024-07-01T10:00:00Z"}`))
+		case "/projects/acme%2Fplatform%2Fapi/languages":
+			_, _ = w.Write([]byte(`{"Ruby": 12.5, "Go": 80.1, "Shell": 7.4}`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	repo, err := NewGitlab(httpclient.WithBaseURL(srv.URL), httpclient.WithToken("secret")).FetchRepo(context.Background(), "acme/platform", "api")
+	require.NoError(t, err)
+
+	assert.Equal(t, "api", repo.Name)
+	assert.Equal(t, "acme/platform", repo.Owner)
+	assert.Equal(t, "Go", repo.Language)
+	assert.Equal(t, 7, repo.StarsCount)
+	assert.True(t, repo.Fork)
+}
+
+func TestFetchCommitsFollowsNextPage(t *testing.T) {
+	since := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/projects/acme/api/repository/commits", r.URL.Path)
+		assert.Equal(t, "2024-07-01T00:00:00Z", r.URL.Query().Get("since"))
+
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set(nextPageHeader, "2")
+			_, _ = w.Write([]byte(`[{"id": "b2", "author_name": "ada"}]`))
+			return
+		}
+		_, _ = w.Write([]byte(`[{"id": "a1", "author_name": "ada"}]`))
+	}))
+	defer srv.Close()
+
+	var shas []string
+	err := NewGitlab(httpclient.WithBaseURL(srv.URL)).FetchCommits(context.Background(), "acme", "api", object.CommitOptions{Since: since}, func(page []object.Commit) error {
+		for _, c := range page {
+			shas = append(shas, c.SHA)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"b2", "a1"}, shas)
+}
+
+func TestRateLimitedResponse(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Truncate(time.Second)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(rateLimitLimitHeader, "600")
+		w.Header().Set(rateLimitRemainingHeader, "0")
+		w.Header().Set(rateLimitResetHeader, strconv.FormatInt(reset.Unix(), 10))
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	details := NewGitlab(httpclient.WithBaseURL(srv.URL))
+	_, err := details.FetchRepo(context.Background(), "acme", "api")
+
+	var rateLimited *object.RateLimitError
+	require.True(t, errors.As(err, &rateLimited))
+	assert.True(t, rateLimited.Reset.Equal(reset))
+
+	limits := details.(object.RateLimitReporter).RateLimits()
+	require.Len(t, limits, 1)
+	assert.Equal(t, 600, limits[0].Limit)
+	assert.Equal(t, 0, limits[0].Remaining)
+}
@@ -0,0 +1,74 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+// project is the project representation of the v4 API.
+type project struct {
+	ID          int    `json:"id"`
+	Path        string `json:"path"`
+	Description string `json:"description"`
+	WebURL      string `json:"web_url"`
+	Namespace   struct {
+		FullPath string `json:"full_path"`
+	} `json:"namespace"`
+	ForksCount        int              `json:"forks_count"`
+	StarCount         int              `json:"star_count"`
+	OpenIssuesCount   int              `json:"open_issues_count"`
+	CreatedAt         time.Time        `json:"created_at"`
+	LastActivityAt    time.Time        `json:"last_activity_at"`
+	Archived          bool             `json:"archived"`
+	ForkedFromProject *json.RawMessage `json:"forked_from_project"`
+}
+
+func (p project) toObject() object.Repository {
+	return object.Repository{
+		Name:            p.Path,
+		Owner:           p.Namespace.FullPath,
+		Description:     p.Description,
+		URL:             p.WebURL,
+		ForksCount:      p.ForksCount,
+		StarsCount:      p.StarCount,
+		OpenIssuesCount: p.OpenIssuesCount,
+		CreatedAt:       p.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       p.LastActivityAt.Format(time.RFC3339),
+		Fork:            p.ForkedFromProject != nil,
+		Archived:        p.Archived,
+	}
+}
+
+type commit struct {
+	ID            string    `json:"id"`
+	AuthorName    string    `json:"author_name"`
+	AuthorEmail   string    `json:"author_email"`
+	AuthoredDate  time.Time `json:"authored_date"`
+	CommittedDate time.Time `json:"committed_date"`
+	Message       string    `json:"message"`
+}
+
+func (c commit) toObject() object.Commit {
+	return object.Commit{
+		SHA:         c.ID,
+		AuthorName:  c.AuthorName,
+		AuthorEmail: c.AuthorEmail,
+		Message:     c.Message,
+		Date:        c.AuthoredDate,
+		CommittedAt: c.CommittedDate,
+	}
+}
+
+// projectPages decodes pages of projects, handing those keep accepts, or all of
+// them when keep is nil, to handle.
+func projectPages(keep func(object.Repository) bool, handle object.RepoPageFunc) func(body []byte) error {
+	return func(body []byte) error {
+		var projects []project
+		if err := json.Unmarshal(body, &projects); err != nil {
+			return err
+		}
+		return object.HandleRepos(projects, project.toObject, keep, handle)
+	}
+}
@@ -0,0 +1,99 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+// searchOrderBy maps the sorts of object.SearchQuery onto GitLab's order_by.
+var searchOrderBy = map[string]string{
+	"stars":   "star_count",
+	"updated": "last_activity_at",
+}
+
+// ValidateSearchQuery reports whether the projects API can serve query. GitLab
+// cannot sort by forks or help-wanted issues.
+func (g *gitlab) ValidateSearchQuery(q object.SearchQuery) error {
+	if strings.TrimSpace(q.Keywords) == "" && q.Language == "" && len(q.Topics) == 0 && q.Org == "" {
+		return fmt.Errorf("search query needs keywords or a language, topic or group qualifier")
+	}
+	if q.StarsAbove < 0 {
+		return fmt.Errorf("invalid search star count %d", q.StarsAbove)
+	}
+	switch q.Fork {
+	case object.ForksExcluded, object.ForksIncluded, object.ForksOnly:
+	default:
+		return fmt.Errorf("invalid search fork filter %q", q.Fork)
+	}
+	if _, ok := searchOrderBy[q.Sort]; q.Sort != "" && !ok {
+		return fmt.Errorf("gitlab cannot sort projects by %q", q.Sort)
+	}
+	if q.Order != "" && q.Order != "asc" && q.Order != "desc" {
+		return fmt.Errorf("invalid search order %q", q.Order)
+	}
+
+	return nil
+}
+
+// SearchRepos hands every project matching query to handle. Qualifiers the
+// projects API has no parameter for, stars and forks, are applied to the results.
+func (g *gitlab) SearchRepos(ctx context.Context, q object.SearchQuery, handle object.RepoPageFunc) error {
+	if err := g.ValidateSearchQuery(q); err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	params.Set("per_page", strconv.Itoa(perPage))
+	if keywords := strings.TrimSpace(q.Keywords); keywords != "" {
+		params.Set("search", keywords)
+	}
+	if q.Language != "" {
+		params.Set("with_programming_language", q.Language)
+	}
+	if len(q.Topics) > 0 {
+		params.Set("topic", strings.Join(q.Topics, ","))
+	}
+	if !q.PushedAfter.IsZero() {
+		params.Set("last_activity_after", q.PushedAfter.UTC().Format(time.RFC3339))
+	}
+	if q.Archived != nil {
+		params.Set("archived", strconv.FormatBool(*q.Archived))
+	}
+	if q.Sort != "" {
+		params.Set("order_by", searchOrderBy[q.Sort])
+		order := q.Order
+		if order == "" {
+			order = "desc"
+		}
+		params.Set("sort", order)
+	}
+
+	searchURL := fmt.Sprintf("%s/projects?%s", g.baseURL, params.Encode())
+	resource := "projects"
+	if q.Org != "" {
+		params.Set("include_subgroups", "true")
+		searchURL = fmt.Sprintf("%s/groups/%s/projects?%s", g.baseURL, url.PathEscape(q.Org), params.Encode())
+		resource = fmt.Sprintf("group %s", q.Org)
+	}
+
+	keep := func(rr object.Repository) bool {
+		if q.StarsAbove > 0 && rr.StarsCount <= q.StarsAbove {
+			return false
+		}
+		switch q.Fork {
+		case object.ForksExcluded:
+			return !rr.Fork
+		case object.ForksOnly:
+			return rr.Fork
+		}
+		return true
+	}
+
+	return g.paginate(ctx, searchURL, resource, 0, projectPages(keep, handle))
+}
@@ -0,0 +1,157 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/object"
+)
+
+// DefaultRetryAfter is assumed for throttled requests without a Retry-After header.
+const DefaultRetryAfter = time.Minute
+
+// ErrStop ends a Paginate early without failing it.
+var ErrStop = errors.New("stop paginating")
+
+// Options configure the API client of a provider. Every provider fills in the
+// defaults from its own environment variables before applying the Option list.
+type Options struct {
+	BaseURL string
+	// Token is an access token, or the password of Username for basic auth.
+	Token      string
+	Username   string
+	HTTPClient *http.Client
+}
+
+// Option customises the API client of a provider.
+type Option func(*Options)
+
+// WithHTTPClient sends every request through httpClient instead of a client
+// built from ConfigFromEnv, e.g. to add middlewares.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *Options) {
+		o.HTTPClient = httpClient
+	}
+}
+
+// WithBaseURL points the client at the API under baseURL instead of the one
+// configured in the environment.
+func WithBaseURL(baseURL string) Option {
+	return func(o *Options) {
+		o.BaseURL = baseURL
+	}
+}
+
+// WithToken authenticates with token instead of the one configured in the
+// environment.
+func WithToken(token string) Option {
+	return func(o *Options) {
+		o.Token = token
+	}
+}
+
+// Apply returns o with opts applied and the trailing slash of the base URL
+// trimmed, along with a resty client sending through the configured HTTP client.
+func (o Options) Apply(opts ...Option) (Options, *resty.Client) {
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.BaseURL = strings.TrimRight(o.BaseURL, "/")
+	if o.HTTPClient == nil {
+		o.HTTPClient = New(ConfigFromEnv())
+	}
+
+	return o, resty.NewWithClient(o.HTTPClient)
+}
+
+// Host names the host of the API at baseURL in lower case, fallback when
+// baseURL has none.
+func Host(baseURL, fallback string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return fallback
+	}
+	return strings.ToLower(u.Host)
+}
+
+// CheckResponse turns an unsuccessful response into one of the typed errors of
+// package object. resource names what was requested, for NotFoundError, and
+// message extracts the message of the provider's error body, the whole body
+// being used when it finds none. Throttled requests are reported as a
+// SecondaryRateLimitError lasting the Retry-After of the response.
+func CheckResponse(resp *resty.Response, resource string, message func(body []byte) string) error {
+	if resp.IsSuccess() {
+		return nil
+	}
+
+	status := resp.StatusCode()
+	switch status {
+	case http.StatusNotFound:
+		return &object.NotFoundError{Resource: resource}
+	case http.StatusUnauthorized, http.StatusForbidden:
+		msg := message(resp.Body())
+		if msg == "" {
+			msg = string(resp.Body())
+		}
+		return &object.UnauthorizedError{StatusCode: status, Message: msg}
+	case http.StatusTooManyRequests:
+		return &object.SecondaryRateLimitError{RetryAfter: RetryAfter(resp.Header())}
+	}
+
+	return &object.UpstreamError{StatusCode: status, Body: string(resp.Body())}
+}
+
+// RetryAfter reads the Retry-After header, DefaultRetryAfter when it is missing.
+func RetryAfter(header http.Header) time.Duration {
+	if wait, ok := parseRetryAfter(header.Get("Retry-After")); ok {
+		return wait
+	}
+	return DefaultRetryAfter
+}
+
+// PageFunc handles a page and returns the URL of the next one, empty after the
+// last page.
+type PageFunc func(resp *resty.Response) (next string, err error)
+
+// Paginate requests firstURL through get, then every page handle names in turn,
+// up to maxPages pages when it is positive; it returns object.ErrTruncated when
+// pages were left. get turns unsuccessful responses into errors, and rate
+// limits hit midway are waited out through the waiter of ctx. handle returning
+// ErrStop ends the pagination without an error.
+func Paginate(ctx context.Context, firstURL string, maxPages int, get func(ctx context.Context, url string) (*resty.Response, error), handle PageFunc) error {
+	pageURL := firstURL
+	for page := 1; pageURL != ""; page++ {
+		if maxPages > 0 && page > maxPages {
+			return object.ErrTruncated
+		}
+
+		resp, err := get(ctx, pageURL)
+		if err != nil {
+			retry, waitErr := object.Wait(ctx, err)
+			if waitErr != nil {
+				return waitErr
+			}
+			if retry {
+				page--
+				continue
+			}
+			return err
+		}
+
+		next, err := handle(resp)
+		if errors.Is(err, ErrStop) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		pageURL = next
+	}
+
+	return nil
+}
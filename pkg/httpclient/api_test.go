@@ -0,0 +1,87 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckResponseMapsStatuses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/throttled":
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/throttled-without-header":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message": "token expired"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	_, client := Options{HTTPClient: srv.Client()}.Apply()
+	check := func(path string) error {
+		resp, err := client.R().Get(srv.URL + path)
+		require.NoError(t, err)
+		return CheckResponse(resp, "repository acme/api", func(body []byte) string {
+			return "expired"
+		})
+	}
+
+	var secondary *object.SecondaryRateLimitError
+	require.ErrorAs(t, check("/throttled"), &secondary)
+	assert.Equal(t, 30*time.Second, secondary.RetryAfter)
+	require.ErrorAs(t, check("/throttled-without-header"), &secondary)
+	assert.Equal(t, DefaultRetryAfter, secondary.RetryAfter)
+
+	var unauthorized *object.UnauthorizedError
+	require.ErrorAs(t, check("/forbidden"), &unauthorized)
+	assert.Equal(t, "expired", unauthorized.Message)
+
+	var notFound *object.NotFoundError
+	require.ErrorAs(t, check("/missing"), &notFound)
+	assert.Equal(t, "repository acme/api", notFound.Resource)
+}
+
+func TestPaginateStopsAtMaxPages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Query().Get("page")))
+	}))
+	defer srv.Close()
+
+	_, client := Options{HTTPClient: srv.Client()}.Apply()
+	get := func(ctx context.Context, url string) (*resty.Response, error) {
+		return client.R().SetContext(ctx).Get(url)
+	}
+	paginate := func(maxPages, stopAt int) ([]string, error) {
+		var pages []string
+		err := Paginate(context.Background(), srv.URL+"?page=1", maxPages, get, func(resp *resty.Response) (string, error) {
+			pages = append(pages, resp.String())
+			if len(pages) == stopAt {
+				return "", ErrStop
+			}
+			return fmt.Sprintf("%s?page=%d", srv.URL, len(pages)+1), nil
+		})
+		return pages, err
+	}
+
+	pages, err := paginate(3, 0)
+	assert.ErrorIs(t, err, object.ErrTruncated)
+	assert.Equal(t, []string{"1", "2", "3"}, pages)
+
+	pages, err = paginate(3, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, pages)
+}
@@ -0,0 +1,161 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker is a circuit breaker for the requests to one host. It opens after a
+// run of failures, refusing every request with an object.UnavailableError
+// until the cooldown is over, then lets a few probes through half-open: the
+// first success closes it again, a failure opens it for another cooldown.
+type breaker struct {
+	host      string
+	failures  int
+	cooldown  time.Duration
+	maxProbes int
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failed   int
+	openedAt time.Time
+	probes   int
+}
+
+// allow reports whether a request may be sent, and whether it is a probe.
+func (b *breaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen {
+		wait := b.openedAt.Add(b.cooldown).Sub(b.now())
+		if wait > 0 {
+			return false, &object.UnavailableError{Host: b.host, RetryAfter: wait}
+		}
+		b.transition(breakerHalfOpen)
+	}
+	if b.state == breakerHalfOpen {
+		if b.probes >= b.maxProbes {
+			return false, &object.UnavailableError{Host: b.host, RetryAfter: b.cooldown}
+		}
+		b.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// record counts the outcome of a request allow let through.
+func (b *breaker) record(probe, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probes--
+	}
+	switch {
+	case !failed:
+		b.failed = 0
+		if b.state == breakerHalfOpen {
+			b.transition(breakerClosed)
+		}
+	case b.state == breakerHalfOpen:
+		b.openedAt = b.now()
+		b.transition(breakerOpen)
+	case b.state == breakerClosed:
+		b.failed++
+		if b.failed >= b.failures {
+			b.openedAt = b.now()
+			b.transition(breakerOpen)
+		}
+	}
+}
+
+// abandon gives back a request allow let through without an outcome.
+func (b *breaker) abandon(probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probes--
+	}
+}
+
+func (b *breaker) transition(to breakerState) {
+	if b.state == to {
+		return
+	}
+	log.Printf("circuit breaker for %s %s -> %s", b.host, b.state, to)
+	b.state = to
+	if to != breakerHalfOpen {
+		b.probes = 0
+	}
+	if to == breakerClosed {
+		b.failed = 0
+	}
+}
+
+// breakerTransport keeps a breaker per host in front of next.
+type breakerTransport struct {
+	next      http.RoundTripper
+	failures  int
+	cooldown  time.Duration
+	maxProbes int
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.breaker(req.URL.Host)
+	probe, err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	// a request the caller gave up on says nothing about the host
+	if err != nil && (errors.Is(err, context.Canceled) || req.Context().Err() != nil) {
+		b.abandon(probe)
+		return nil, err
+	}
+	b.record(probe, err != nil || resp.StatusCode >= http.StatusInternalServerError)
+	return resp, err
+}
+
+func (t *breakerTransport) breaker(host string) *breaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.breakers[host]
+	if !ok {
+		b = &breaker{host: host, failures: t.failures, cooldown: t.cooldown, maxProbes: t.maxProbes, now: time.Now}
+		t.breakers[host] = b
+	}
+	return b
+}
@@ -0,0 +1,72 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakerOpensAfterFailures(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.MaxRetries = -1
+	cfg.BreakerFailures = 2
+	cfg.BreakerCooldown = time.Hour
+	client := New(cfg)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	_, err := client.Get(srv.URL)
+	var unavailable *object.UnavailableError
+	require.ErrorAs(t, err, &unavailable)
+	assert.Greater(t, unavailable.RetryAfter, 59*time.Minute)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestBreakerHalfOpenProbe(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	b := &breaker{host: "api.github.com", failures: 1, cooldown: time.Minute, maxProbes: 1, now: func() time.Time { return now }}
+
+	probe, err := b.allow()
+	require.NoError(t, err)
+	b.record(probe, true)
+	assert.Equal(t, breakerOpen, b.state)
+
+	_, err = b.allow()
+	assert.Error(t, err)
+
+	// after the cooldown a single probe goes through, and its failure reopens
+	now = now.Add(time.Minute)
+	probe, err = b.allow()
+	require.NoError(t, err)
+	assert.True(t, probe)
+	_, err = b.allow()
+	assert.Error(t, err)
+	b.record(probe, true)
+	assert.Equal(t, breakerOpen, b.state)
+
+	// a successful probe closes it again
+	now = now.Add(time.Minute)
+	probe, err = b.allow()
+	require.NoError(t, err)
+	b.record(probe, false)
+	assert.Equal(t, breakerClosed, b.state)
+	_, err = b.allow()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,191 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultTimeout             = 30 * time.Second
+	defaultMaxIdleConnsPerHost = 10
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultMaxRetries          = 3
+	defaultMinBackoff          = 500 * time.Millisecond
+	defaultMaxBackoff          = 30 * time.Second
+	defaultBreakerFailures     = 5
+	defaultBreakerCooldown     = 30 * time.Second
+	defaultBreakerProbes       = 1
+)
+
+// Config tunes the client built by New. Zero values take the defaults.
+type Config struct {
+	// Timeout bounds a single attempt, including reading the body.
+	Timeout             time.Duration
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	// MaxRetries is how often an idempotent request is repeated after a network
+	// error or a 5xx response; negative disables retries.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the exponential backoff between attempts.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	TLS        *tls.Config
+	// BreakerFailures is how many requests in a row have to fail, after their
+	// retries, for the circuit breaker of a host to open; negative disables it.
+	BreakerFailures int
+	// BreakerCooldown is how long an open breaker refuses requests before it
+	// lets BreakerProbes requests through to test the host.
+	BreakerCooldown time.Duration
+	BreakerProbes   int
+}
+
+// ConfigFromEnv reads the optional HTTP_TIMEOUT, HTTP_IDLE_CONN_TIMEOUT and
+// HTTP_BREAKER_COOLDOWN durations and the HTTP_MAX_RETRIES,
+// HTTP_MAX_IDLE_CONNS_PER_HOST, HTTP_BREAKER_FAILURES and HTTP_BREAKER_PROBES
+// counts. HTTP_MAX_RETRIES=0 disables retries, HTTP_BREAKER_FAILURES=0 the
+// circuit breaker.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Timeout:             durationFromEnv("HTTP_TIMEOUT"),
+		IdleConnTimeout:     durationFromEnv("HTTP_IDLE_CONN_TIMEOUT"),
+		MaxRetries:          intFromEnv("HTTP_MAX_RETRIES"),
+		MaxIdleConnsPerHost: intFromEnv("HTTP_MAX_IDLE_CONNS_PER_HOST"),
+		BreakerFailures:     intFromEnv("HTTP_BREAKER_FAILURES"),
+		BreakerCooldown:     durationFromEnv("HTTP_BREAKER_COOLDOWN"),
+		BreakerProbes:       intFromEnv("HTTP_BREAKER_PROBES"),
+	}
+	if cfg.MaxRetries == 0 && os.Getenv("HTTP_MAX_RETRIES") == "0" {
+		cfg.MaxRetries = -1
+	}
+	if cfg.BreakerFailures == 0 && os.Getenv("HTTP_BREAKER_FAILURES") == "0" {
+		cfg.BreakerFailures = -1
+	}
+	return cfg
+}
+
+// Middleware wraps a RoundTripper, e.g. to log, measure or fail requests.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc turns a function into an http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// New returns a long-lived client for one provider, pooling its connections.
+// Requests pass through middlewares in order, the first outermost, then through
+// the circuit breaker of their host and the retries, so middlewares see every
+// request once, the breaker the outcome after retrying, and the transport every
+// attempt.
+func New(cfg Config, middlewares ...Middleware) *http.Client {
+	cfg = withDefaults(cfg)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
+	transport.DialContext = (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	if cfg.TLS != nil {
+		transport.TLSClientConfig = cfg.TLS
+	}
+
+	var rt http.RoundTripper = &retryTransport{
+		next:       transport,
+		maxRetries: cfg.MaxRetries,
+		minBackoff: cfg.MinBackoff,
+		maxBackoff: cfg.MaxBackoff,
+		timeout:    cfg.Timeout,
+	}
+	if cfg.BreakerFailures > 0 {
+		rt = &breakerTransport{
+			next:      rt,
+			failures:  cfg.BreakerFailures,
+			cooldown:  cfg.BreakerCooldown,
+			maxProbes: cfg.BreakerProbes,
+			breakers:  make(map[string]*breaker),
+		}
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		rt = middlewares[i](rt)
+	}
+
+	return &http.Client{Transport: rt}
+}
+
+// Logging logs the method, URL, status and duration of every request.
+func Logging(logger *log.Logger) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			started := time.Now()
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				logger.Printf("%s %s failed after %s: %v", req.Method, req.URL.Redacted(), time.Since(started), err)
+				return nil, err
+			}
+			logger.Printf("%s %s %d in %s", req.Method, req.URL.Redacted(), resp.StatusCode, time.Since(started))
+			return resp, nil
+		})
+	}
+}
+
+func withDefaults(cfg Config) Config {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxIdleConnsPerHost <= 0 {
+		cfg.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+	if cfg.IdleConnTimeout <= 0 {
+		cfg.IdleConnTimeout = defaultIdleConnTimeout
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = defaultMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = defaultMaxBackoff
+	}
+	if cfg.BreakerFailures == 0 {
+		cfg.BreakerFailures = defaultBreakerFailures
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = defaultBreakerCooldown
+	}
+	if cfg.BreakerProbes <= 0 {
+		cfg.BreakerProbes = defaultBreakerProbes
+	}
+	return cfg
+}
+
+func durationFromEnv(name string) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("invalid %s %q, using the default", name, v)
+		return 0
+	}
+	return d
+}
+
+func intFromEnv(name string) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("invalid %s %q, using the default", name, v)
+		return 0
+	}
+	return n
+}
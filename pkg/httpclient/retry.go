@@ -0,0 +1,129 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// retryTransport repeats idempotent requests that failed with a network error
+// or a 5xx response, backing off exponentially with jitter. A Retry-After the
+// server sends is honoured when it is within the largest backoff; longer waits,
+// like the rate limits of 403 and 429 responses, are left to the caller.
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	// timeout bounds every attempt, until its body is closed.
+	timeout time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+		resp, err := t.next.RoundTrip(req.Clone(ctx))
+
+		wait, retry := t.retryAfter(req, attempt, resp, err)
+		if !retry {
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		if resp != nil {
+			// drain the body so the connection goes back to the pool
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		cancel()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryAfter reports whether the outcome of attempt is worth repeating, and how
+// long to wait before doing so.
+func (t *retryTransport) retryAfter(req *http.Request, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= t.maxRetries || !idempotent(req) || req.Context().Err() != nil {
+		return 0, false
+	}
+
+	if err != nil {
+		return t.backoff(attempt), true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+	default:
+		return 0, false
+	}
+
+	if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		return wait, wait <= t.maxBackoff
+	}
+	return t.backoff(attempt), true
+}
+
+// backoff doubles from minBackoff with every attempt, up to maxBackoff, and
+// picks a random wait in the upper half so clients do not retry in lockstep.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	d := t.maxBackoff
+	if attempt < 32 {
+		if exp := t.minBackoff << attempt; exp > 0 && exp < d {
+			d = exp
+		}
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// idempotent reports whether req can be sent again without side effects.
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		wait := time.Until(at)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// cancelOnClose releases the context of an attempt once its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
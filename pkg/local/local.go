@@ -0,0 +1,321 @@
+package local
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/project/pkg/object"
+)
+
+const (
+	// commitsPerPage is the number of commits handed to FetchCommits callers at a time.
+	commitsPerPage = 100
+
+	// defaultDescription is the description git init writes, which says nothing.
+	defaultDescription = "Unnamed repository; edit this file 'description' to name the repository."
+
+	// Host is the host local repositories are stored under.
+	Host = "local"
+)
+
+// separators of the git log format: fields by NUL, records by RS
+const (
+	fieldSeparator  = "\x00"
+	recordSeparator = '\x1e'
+	logFormat       = "--format=%H%x00%an%x00%ae%x00%aI%x00%cI%x00%B%x1e"
+)
+
+type local struct {
+	root string
+}
+
+// Option customises the provider built by NewLocal.
+type Option func(*local)
+
+// WithRoot reads repositories below root instead of GIT_LOCAL_ROOT.
+func WithRoot(root string) Option {
+	return func(l *local) {
+		l.root = root
+	}
+}
+
+// NewLocal returns a GitDetails reading clones on disk with the git binary,
+// without any network access. Repositories are laid out as <root>/<owner>/<name>,
+// bare (<name>.git) or with a working tree. GIT_LOCAL_ROOT may be a path or a
+// file:// URL.
+func NewLocal(opts ...Option) object.GitDetails {
+	l := &local{root: os.Getenv("GIT_LOCAL_ROOT")}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if u, err := url.Parse(l.root); err == nil && u.Scheme == "file" {
+		l.root = u.Path
+	}
+
+	return l
+}
+
+func (l *local) FetchRepo(ctx context.Context, owner, repo string) (*object.Repository, error) {
+	dir, err := l.repoDir(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.describe(ctx, owner, repo, dir)
+}
+
+// FetchCommits walks the history of HEAD, newest first.
+func (l *local) FetchCommits(ctx context.Context, owner, repo string, opts object.CommitOptions, handle object.CommitPageFunc) error {
+	dir, err := l.repoDir(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+	if _, err := git(ctx, dir, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		// nothing has been committed yet
+		return nil
+	}
+
+	args := []string{"log", logFormat}
+	if !opts.Since.IsZero() {
+		args = append(args, "--since="+opts.Since.Format(time.RFC3339))
+	}
+	if !opts.Until.IsZero() {
+		args = append(args, "--until="+opts.Until.Format(time.RFC3339))
+	}
+	args = append(args, "HEAD")
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	err = readCommits(stdout, handle)
+	if err != nil {
+		// stop git from blocking on a pipe nobody reads any more
+		_ = cmd.Process.Kill()
+	}
+	if waitErr := cmd.Wait(); err == nil && waitErr != nil {
+		err = fmt.Errorf("git log in %s: %v: %s", dir, waitErr, strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+// ListOrgRepos hands every repository below <root>/<org> to handle.
+func (l *local) ListOrgRepos(ctx context.Context, org string, handle object.RepoPageFunc) error {
+	return l.listOwner(ctx, org, nil, handle)
+}
+
+// ListUserRepos hands every repository below <root>/<user> to handle; on disk
+// organizations and users are both directories.
+func (l *local) ListUserRepos(ctx context.Context, user string, handle object.RepoPageFunc) error {
+	return l.listOwner(ctx, user, nil, handle)
+}
+
+// listOwner hands the repositories of owner keep accepts, or all of them when
+// keep is nil, to handle as a single page.
+func (l *local) listOwner(ctx context.Context, owner string, keep func(object.Repository) bool, handle object.RepoPageFunc) error {
+	if !validSegment(owner) {
+		return &object.NotFoundError{Resource: fmt.Sprintf("owner %s", owner)}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(l.root, owner))
+	if errors.Is(err, os.ErrNotExist) {
+		return &object.NotFoundError{Resource: fmt.Sprintf("owner %s", owner)}
+	}
+	if err != nil {
+		return err
+	}
+
+	var page []object.Repository
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".git")
+		dir := filepath.Join(l.root, owner, entry.Name())
+		if !isRepository(ctx, dir) {
+			continue
+		}
+
+		repository, err := l.describe(ctx, owner, name, dir)
+		if err != nil {
+			return err
+		}
+		if keep == nil || keep(*repository) {
+			page = append(page, *repository)
+		}
+	}
+	if len(page) == 0 {
+		return nil
+	}
+	return handle(page)
+}
+
+// repoDir finds the clone of owner/repo, bare or with a working tree.
+func (l *local) repoDir(ctx context.Context, owner, repo string) (string, error) {
+	if validSegment(owner) && validSegment(repo) {
+		for _, name := range []string{repo + ".git", repo} {
+			dir := filepath.Join(l.root, owner, name)
+			if isRepository(ctx, dir) {
+				return dir, nil
+			}
+		}
+	}
+
+	return "", &object.NotFoundError{Resource: fmt.Sprintf("repository %s/%s", owner, repo)}
+}
+
+// describe derives the metadata of the clone in dir from its config and history.
+func (l *local) describe(ctx context.Context, owner, repo, dir string) (*object.Repository, error) {
+	repository := &object.Repository{
+		Name:  repo,
+		Owner: owner,
+		URL:   (&url.URL{Scheme: "file", Path: dir}).String(),
+	}
+
+	if remote, err := git(ctx, dir, "config", "--get", "remote.origin.url"); err == nil && remote != "" {
+		repository.URL = remote
+	}
+
+	gitDir, err := git(ctx, dir, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return nil, err
+	}
+	if description, err := os.ReadFile(filepath.Join(gitDir, "description")); err == nil {
+		if d := strings.TrimSpace(string(description)); d != defaultDescription {
+			repository.Description = d
+		}
+	}
+
+	// an empty repository has no dates yet
+	if updated, err := git(ctx, dir, "log", "-1", "--format=%cI", "HEAD"); err == nil {
+		repository.UpdatedAt = utcTime(updated)
+	}
+	if roots, err := git(ctx, dir, "log", "--max-parents=0", "--format=%cI", "HEAD"); err == nil && roots != "" {
+		lines := strings.Split(roots, "\n")
+		repository.CreatedAt = utcTime(lines[len(lines)-1])
+	}
+
+	return repository, nil
+}
+
+// readCommits decodes the records of git log, handing them to handle a page at a time.
+func readCommits(r io.Reader, handle object.CommitPageFunc) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, recordSeparator); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(bytes.TrimSpace(data)) > 0 {
+			return len(data), data, nil
+		}
+		if atEOF {
+			return len(data), nil, nil
+		}
+		return 0, nil, nil
+	})
+
+	page := make([]object.Commit, 0, commitsPerPage)
+	for scanner.Scan() {
+		record := strings.TrimLeft(scanner.Text(), "\n")
+		fields := strings.SplitN(record, fieldSeparator, 6)
+		if len(fields) < 6 {
+			continue
+		}
+
+		date, err := time.Parse(time.RFC3339, fields[3])
+		if err != nil {
+			return err
+		}
+		committedAt, err := time.Parse(time.RFC3339, fields[4])
+		if err != nil {
+			return err
+		}
+		page = append(page, object.Commit{
+			SHA:         fields[0],
+			AuthorName:  fields[1],
+			AuthorEmail: fields[2],
+			Date:        date,
+			CommittedAt: committedAt,
+			Message:     strings.TrimSpace(fields[5]),
+		})
+
+		if len(page) == commitsPerPage {
+			if err := handle(page); err != nil {
+				return err
+			}
+			page = make([]object.Commit, 0, commitsPerPage)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if len(page) == 0 {
+		return nil
+	}
+	return handle(page)
+}
+
+// utcTime renders a strict ISO 8601 date of git in UTC, like the dates of the
+// hosted providers.
+func utcTime(iso string) string {
+	t, err := time.Parse(time.RFC3339, iso)
+	if err != nil {
+		return iso
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// git runs git in dir and returns its trimmed output.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// isRepository reports whether dir is a clone, bare or with a working tree.
+func isRepository(ctx context.Context, dir string) bool {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return false
+	}
+	// rev-parse also succeeds in any directory below a clone, which must not
+	// count as a repository of its own
+	gitDir, err := git(ctx, dir, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return false
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	return gitDir == abs || gitDir == filepath.Join(abs, ".git")
+}
+
+// validSegment reports whether name is a single path segment, so owners and
+// names cannot reach outside the root.
+func validSegment(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && !strings.ContainsAny(name, `/\`)
+}
@@ -0,0 +1,85 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newClone creates <root>/<owner>/<name> with one commit per date, oldest first.
+func newClone(t *testing.T, root, owner, name string, dates ...time.Time) {
+	t.Helper()
+
+	dir := filepath.Join(root, owner, name)
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	run := func(env []string, args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(), env...)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	run(nil, "init", "--quiet")
+	run(nil, "remote", "add", "origin", "https://example.com/"+owner+"/"+name+".git")
+	for _, date := range dates {
+		stamp := date.Format(time.RFC3339)
+		run([]string{
+			"GIT_AUTHOR_NAME=Ada", "GIT_AUTHOR_EMAIL=ada@example.com", "GIT_AUTHOR_DATE=" + stamp,
+			"GIT_COMMITTER_NAME=Ada", "GIT_COMMITTER_EMAIL=ada@example.com", "GIT_COMMITTER_DATE=" + stamp,
+		}, "commit", "--quiet", "--allow-empty", "-m", "commit of "+stamp)
+	}
+}
+
+func TestLocalClones(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	first := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	newClone(t, root, "infra", "deploy", first, first.Add(24*time.Hour), first.Add(48*time.Hour))
+	newClone(t, root, "infra", "empty")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "infra", "notes"), 0o755))
+
+	details := NewLocal(WithRoot("file://" + root))
+	ctx := context.Background()
+
+	repo, err := details.FetchRepo(ctx, "infra", "deploy")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/infra/deploy.git", repo.URL)
+	assert.Equal(t, "2024-06-30T12:00:00Z", repo.CreatedAt)
+	assert.Equal(t, "2024-07-02T12:00:00Z", repo.UpdatedAt)
+
+	var commits []object.Commit
+	err = details.FetchCommits(ctx, "infra", "deploy", object.CommitOptions{Since: first.Add(time.Hour)}, func(page []object.Commit) error {
+		commits = append(commits, page...)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+	assert.Equal(t, "commit of 2024-07-02T12:00:00Z", commits[0].Message)
+	assert.Equal(t, "ada@example.com", commits[0].AuthorEmail)
+	assert.True(t, commits[1].Date.Equal(first.Add(24*time.Hour)))
+
+	var names []string
+	err = details.ListOrgRepos(ctx, "infra", func(page []object.Repository) error {
+		for _, rr := range page {
+			names = append(names, rr.Name)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deploy", "empty"}, names)
+
+	_, err = details.FetchRepo(ctx, "infra", "../infra/deploy")
+	var notFound *object.NotFoundError
+	assert.True(t, errors.As(err, &notFound))
+}
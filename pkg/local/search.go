@@ -0,0 +1,72 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/project/pkg/object"
+)
+
+// ValidateSearchQuery reports whether local clones can serve query. They are
+// matched on their name and description only; there are no languages, stars
+// or topics on disk to filter or sort by.
+func (l *local) ValidateSearchQuery(q object.SearchQuery) error {
+	if strings.TrimSpace(q.Keywords) == "" && q.Org == "" {
+		return fmt.Errorf("search query needs keywords or an org qualifier")
+	}
+	if q.Language != "" || q.StarsAbove != 0 || len(q.Topics) > 0 || !q.PushedAfter.IsZero() {
+		return fmt.Errorf("local repositories can only be searched by keywords and org")
+	}
+	if q.Sort != "" {
+		return fmt.Errorf("local repositories cannot be sorted")
+	}
+
+	return nil
+}
+
+// SearchRepos hands the clones whose name or description contains every
+// keyword to handle, a page per owner directory.
+func (l *local) SearchRepos(ctx context.Context, q object.SearchQuery, handle object.RepoPageFunc) error {
+	if err := l.ValidateSearchQuery(q); err != nil {
+		return err
+	}
+
+	keywords := strings.Fields(strings.ToLower(q.Keywords))
+	keep := func(rr object.Repository) bool {
+		if q.Archived != nil && *q.Archived {
+			return false
+		}
+		if q.Fork == object.ForksOnly {
+			return false
+		}
+
+		text := strings.ToLower(rr.Name + " " + rr.Description)
+		for _, keyword := range keywords {
+			if !strings.Contains(text, keyword) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if q.Org != "" {
+		return l.listOwner(ctx, q.Org, keep, handle)
+	}
+
+	entries, err := os.ReadDir(l.root)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !validSegment(entry.Name()) {
+			continue
+		}
+		if err := l.listOwner(ctx, entry.Name(), keep, handle); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
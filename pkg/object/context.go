@@ -0,0 +1,81 @@
+package object
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotModified is returned by GitDetails implementations when the upstream
+// confirmed the requested resource has not changed since it was last fetched.
+var ErrNotModified = errors.New("not modified")
+
+type forceRefreshKey struct{}
+
+// WithForceRefresh marks ctx so providers skip their conditional-request caches
+// and always return the full resource.
+func WithForceRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceRefreshKey{}, true)
+}
+
+// IsForceRefresh reports whether ctx was marked with WithForceRefresh.
+func IsForceRefresh(ctx context.Context) bool {
+	force, _ := ctx.Value(forceRefreshKey{}).(bool)
+	return force
+}
+
+// Waiter blocks until a request that failed with err may be retried. It reports
+// retry == false when err is not worth retrying, and returns an error when the
+// caller should give up instead, e.g. because ctx was cancelled.
+type Waiter func(ctx context.Context, err error) (retry bool, waitErr error)
+
+type noWaitKey struct{}
+
+// WithoutWaiting marks ctx as interactive: providers report rate limits, and
+// the delays they would pace requests by, as errors instead of blocking.
+func WithoutWaiting(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noWaitKey{}, true)
+}
+
+// IsWaitingAllowed reports whether ctx may block on rate limits, see WithoutWaiting.
+func IsWaitingAllowed(ctx context.Context) bool {
+	noWait, _ := ctx.Value(noWaitKey{}).(bool)
+	return !noWait
+}
+
+type waiterKey struct{}
+
+// WithWaiter lets providers wait out rate limits in the middle of a long
+// pagination instead of failing and losing their progress.
+func WithWaiter(ctx context.Context, waiter Waiter) context.Context {
+	return context.WithValue(ctx, waiterKey{}, waiter)
+}
+
+// Wait runs the Waiter attached to ctx, if any.
+func Wait(ctx context.Context, err error) (retry bool, waitErr error) {
+	waiter, ok := ctx.Value(waiterKey{}).(Waiter)
+	if !ok {
+		return false, nil
+	}
+	return waiter(ctx, err)
+}
+
+// Admitter decides whether a request against the rate-limit resource may be
+// sent on behalf of ctx, returning an error to hold it back.
+type Admitter func(ctx context.Context, resource string) error
+
+type admitterKey struct{}
+
+// WithAdmitter lets the caller arbitrate the quota of a provider between the
+// jobs sharing it. Providers that report a Budget ask it before every request.
+func WithAdmitter(ctx context.Context, admitter Admitter) context.Context {
+	return context.WithValue(ctx, admitterKey{}, admitter)
+}
+
+// Admit runs the Admitter attached to ctx, if any.
+func Admit(ctx context.Context, resource string) error {
+	admitter, ok := ctx.Value(admitterKey{}).(Admitter)
+	if !ok {
+		return nil
+	}
+	return admitter(ctx, resource)
+}
@@ -0,0 +1,68 @@
+package object
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateLimitError reports that the primary rate limit of the credential in use is
+// exhausted until Reset.
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded until %s", e.Reset.Format(time.RFC3339))
+}
+
+// SecondaryRateLimitError reports that the upstream throttled a burst of requests
+// even though primary quota is left. Requests may resume after RetryAfter.
+type SecondaryRateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *SecondaryRateLimitError) Error() string {
+	return fmt.Sprintf("secondary rate limit exceeded, retry after %s", e.RetryAfter)
+}
+
+// NotFoundError reports that the requested resource does not exist or is not
+// visible to the credential in use.
+type NotFoundError struct {
+	Resource string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s not found", e.Resource)
+}
+
+// UnauthorizedError reports that the upstream rejected the credential in use, or
+// that it lacks permission for the resource.
+type UnauthorizedError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return fmt.Sprintf("unauthorized (%d): %s", e.StatusCode, e.Message)
+}
+
+// UpstreamError is any other unsuccessful upstream response.
+type UpstreamError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *UpstreamError) Error() string {
+	return fmt.Sprintf("upstream responded with status %d: %s", e.StatusCode, e.Body)
+}
+
+// UnavailableError reports that requests to Host are held back without being
+// sent, because it kept failing. They are let through again after RetryAfter.
+type UnavailableError struct {
+	Host       string
+	RetryAfter time.Duration
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s is unavailable, retry after %s", e.Host, e.RetryAfter.Round(time.Second))
+}
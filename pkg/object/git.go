@@ -2,15 +2,63 @@ package object
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
+// GitDetails reads repositories and their history from a git hosting provider.
+// Unsuccessful upstream responses are reported as RateLimitError,
+// SecondaryRateLimitError, NotFoundError, UnauthorizedError or UpstreamError.
 type GitDetails interface {
-	SearchRepos(ctx context.Context, interest string) ([]Repository, int64, error)
-	FetchRepo(ctx context.Context, owner, repo string) (*Repository, int64, error)
-	FetchCommits(ctx context.Context, owner, repo string) ([]Commit, int64, error)
+	SearchRepos(ctx context.Context, query SearchQuery, handle RepoPageFunc) error
+	FetchRepo(ctx context.Context, owner, repo string) (*Repository, error)
+	FetchCommits(ctx context.Context, owner, repo string, opts CommitOptions, handle CommitPageFunc) error
+	// ListOrgRepos hands every repository of the organization org to handle.
+	ListOrgRepos(ctx context.Context, org string, handle RepoPageFunc) error
+	// ListUserRepos hands every repository owned by the user to handle.
+	ListUserRepos(ctx context.Context, user string, handle RepoPageFunc) error
 }
 
+// RepoPageFunc receives each page of repositories as soon as it has been fetched.
+// Returning an error stops the pagination.
+type RepoPageFunc func(repos []Repository) error
+
+// HandleRepos converts a page of a provider's repositories with toObject and
+// hands the ones keep accepts, every one when keep is nil, to handle. Pages
+// left empty are skipped.
+func HandleRepos[T any](repos []T, toObject func(T) Repository, keep func(Repository) bool, handle RepoPageFunc) error {
+	page := make([]Repository, 0, len(repos))
+	for _, r := range repos {
+		repository := toObject(r)
+		if keep != nil && !keep(repository) {
+			continue
+		}
+		page = append(page, repository)
+	}
+	if len(page) == 0 {
+		return nil
+	}
+	return handle(page)
+}
+
+// CommitOptions narrows the commit history returned by FetchCommits.
+// Zero values leave the corresponding bound open.
+type CommitOptions struct {
+	// Since only returns commits committed at or after this time.
+	Since time.Time
+	// Until only returns commits committed at or before this time.
+	Until time.Time
+}
+
+// CommitPageFunc receives each page of commits as soon as it has been fetched,
+// newest first. Returning an error stops the pagination.
+type CommitPageFunc func(commits []Commit) error
+
+// ErrTruncated is returned by FetchCommits when it stopped at its page cap with
+// older commits of the requested history left unread. The pages handed over
+// before are complete.
+var ErrTruncated = errors.New("history truncated at the page cap")
+
 type Repository struct {
 	Name            string `json:"name"`
 	Owner           string `json:"owner"`
@@ -23,13 +71,101 @@ type Repository struct {
 	WatchersCount   int    `json:"watchers_count"`
 	CreatedAt       string `json:"created_at"`
 	UpdatedAt       string `json:"updated_at"`
+	Fork            bool   `json:"fork"`
+	Archived        bool   `json:"archived"`
 }
 
 type Commit struct {
-	RepoID      uint      `json:"repo_id"`
-	SHA         string    `json:"sha"`
-	AuthorName  string    `json:"author_name"`
-	AuthorEmail string    `json:"author_email"`
-	Message     string    `json:"message"`
-	Date        time.Time `json:"date"`
+	RepoID      uint   `json:"repo_id"`
+	SHA         string `json:"sha"`
+	AuthorName  string `json:"author_name"`
+	AuthorEmail string `json:"author_email"`
+	Message     string `json:"message"`
+	// Date is when the commit was authored.
+	Date time.Time `json:"date"`
+	// CommittedAt is when the commit was applied, which differs from Date for
+	// rebased or cherry-picked commits. The Since and Until bounds of
+	// CommitOptions apply to it.
+	CommittedAt time.Time `json:"committed_at"`
+}
+
+// RateLimit is the request budget a provider last reported for one credential.
+type RateLimit struct {
+	// Host is the provider host the limit was reported for, filled in by callers
+	// serving several providers.
+	Host       string `json:"host,omitempty"`
+	Credential string `json:"credential"`
+	// Resource names the budget the limit applies to, e.g. core, search or graphql.
+	Resource  string    `json:"resource"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	// Cost is the number of points the last query consumed, for APIs that charge per query.
+	Cost int `json:"cost,omitempty"`
+}
+
+// RateLimitReporter is implemented by GitDetails providers that track the quota
+// of the credentials they use.
+type RateLimitReporter interface {
+	RateLimits() []RateLimit
+}
+
+// Rate-limit resources GitHub budgets separately.
+const (
+	ResourceCore    = "core"
+	ResourceSearch  = "search"
+	ResourceGraphQL = "graphql"
+)
+
+// Budget is what is left of one rate-limit resource across every credential of
+// a provider, and the pace at which it is being spent.
+type Budget struct {
+	Resource  string `json:"resource"`
+	Limit     int    `json:"limit"`
+	Remaining int    `json:"remaining"`
+	// Reset is the latest reset among the credentials, after which the whole
+	// budget is available again.
+	Reset time.Time `json:"reset"`
+	// Rate is how many requests per second are let through to spread Remaining
+	// evenly until the reset.
+	Rate float64 `json:"rate"`
+	// Cost is what a request is expected to take of Remaining: the points the
+	// last query cost for APIs that charge per query, one request otherwise.
+	Cost int `json:"cost"`
+}
+
+// BudgetReporter is implemented by GitDetails providers that pace their
+// requests to the quota. ok is false while nothing is known about resource.
+type BudgetReporter interface {
+	Budget(resource string) (budget Budget, ok bool)
+}
+
+// SearchQueryValidator is implemented by GitDetails providers that can tell
+// upfront whether they accept a search query.
+type SearchQueryValidator interface {
+	ValidateSearchQuery(query SearchQuery) error
+}
+
+// RepoRef identifies a repository in a batch request, together with the part of
+// its commit history the caller is interested in.
+type RepoRef struct {
+	Owner   string
+	Name    string
+	Commits CommitOptions
+}
+
+// RepoSnapshot is a repository together with the first page of its commit history.
+type RepoSnapshot struct {
+	Repository
+	Commits []Commit
+	// HasMoreCommits reports that Commits is only the first page of the history
+	// matching the request; the rest has to be read with FetchCommits.
+	HasMoreCommits bool
+}
+
+// BatchFetcher is implemented by GitDetails providers that can fetch several
+// repositories and their recent commits in a single round trip. Repositories
+// that do not exist are left out of the result.
+type BatchFetcher interface {
+	FetchRepos(ctx context.Context, refs []RepoRef) ([]RepoSnapshot, error)
 }
@@ -0,0 +1,36 @@
+package object
+
+import "time"
+
+// ForkFilter controls whether forks are part of a repository search.
+type ForkFilter string
+
+const (
+	// ForksExcluded leaves forks out, the default of most providers.
+	ForksExcluded ForkFilter = ""
+	// ForksIncluded searches forks alongside the repositories they were forked from.
+	ForksIncluded ForkFilter = "true"
+	// ForksOnly searches nothing but forks.
+	ForksOnly ForkFilter = "only"
+)
+
+// SearchQuery is a repository search: free-text keywords narrowed by qualifiers.
+// Zero values leave the corresponding qualifier out.
+type SearchQuery struct {
+	Keywords string `json:"keywords"`
+	Language string `json:"language,omitempty"`
+	// StarsAbove only matches repositories with more stars than this.
+	StarsAbove int `json:"stars_above,omitempty"`
+	// PushedAfter only matches repositories pushed to after this day.
+	PushedAfter time.Time `json:"pushed_after,omitempty"`
+	Topics      []string  `json:"topics,omitempty"`
+	Org         string    `json:"org,omitempty"`
+	// Archived, when set, only matches repositories whose archived state equals it.
+	Archived *bool      `json:"archived,omitempty"`
+	Fork     ForkFilter `json:"fork,omitempty"`
+	// Sort orders the results by stars, forks, help-wanted-issues or updated;
+	// empty sorts by best match.
+	Sort string `json:"sort,omitempty"`
+	// Order is asc or desc, desc when empty.
+	Order string `json:"order,omitempty"`
+}
@@ -2,31 +2,45 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/project/internal/service"
+	"github.com/project/pkg/object"
 )
 
 type Handler struct {
-	service service.IGitInfo
+	service   service.IGitInfo
+	interests service.IInterest
+	owners    service.IOwners
 }
 
-func NewHandler(service service.IGitInfo) *Handler {
-	return &Handler{service: service}
+func NewHandler(service service.IGitInfo, interests service.IInterest, owners service.IOwners) *Handler {
+	return &Handler{service: service, interests: interests, owners: owners}
 }
 
+// FetchRepo serves the stored repository while it is fresh and refreshes it
+// from the provider otherwise, or always with ?refresh=true.
 func (h *Handler) FetchRepo(c *gin.Context) {
 	owner := c.Param("owner")
 	repo := c.Param("repo")
-	ctx := context.Background()
+	ctx := service.WithoutWaiting(c.Request.Context())
+	if refresh, _ := strconv.ParseBool(c.Query("refresh")); refresh {
+		ctx = service.WithRefresh(ctx)
+	}
 
-	repoData, err := h.service.FetchRepo(ctx, owner, repo)
+	repoData, err := h.service.FetchRepo(ctx, c.Query("host"), owner, repo)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
+	if repoData.Stale {
+		markStale(c)
+	}
 
 	c.JSON(http.StatusOK, repoData)
 }
@@ -52,13 +66,16 @@ func (h *Handler) GetTopNRepoByStarCount(c *gin.Context) {
 func (h Handler) FetchCommit(c *gin.Context) {
 	owner := c.Param("owner")
 	repo := c.Param("repo")
-	ctx := context.Background()
+	ctx := service.WithoutWaiting(c.Request.Context())
 
-	commitData, err := h.service.GetCommit(ctx, owner, repo)
+	commitData, stale, err := h.service.GetCommit(ctx, c.Query("host"), owner, repo)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
+	if stale {
+		markStale(c)
+	}
 
 	c.JSON(http.StatusOK, commitData)
 }
@@ -74,3 +91,47 @@ func (h Handler) FetchByLanguage(c *gin.Context) {
 
 	c.JSON(http.StatusOK, repoData)
 }
+
+func (h Handler) GetRateLimits(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.RateLimits())
+}
+
+// markStale flags a response served from storage because the provider was unavailable.
+func markStale(c *gin.Context) {
+	c.Header("Warning", `110 - "Response is Stale"`)
+}
+
+// respondError maps service errors onto the HTTP status returned to the client.
+// Rate limits are answered with 429 and a Retry-After header instead of blocking.
+func respondError(c *gin.Context, err error) {
+	var (
+		notFound    *object.NotFoundError
+		rateLimited *service.RateLimitedError
+		exhausted   *service.BudgetExhaustedError
+		unavailable *object.UnavailableError
+		invalid     *service.ValidationError
+		conflict    *service.ConflictError
+	)
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.As(err, &conflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.As(err, &rateLimited):
+		retryAfter := int(math.Ceil(rateLimited.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.As(err, &exhausted):
+		retryAfter := int(math.Ceil(time.Until(exhausted.Reset).Seconds()))
+		c.Header("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.As(err, &unavailable):
+		retryAfter := int(math.Ceil(unavailable.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(max(retryAfter, 1)))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.As(err, &notFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
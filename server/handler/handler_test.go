@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/project/internal/model"
+	"github.com/project/internal/repository"
+	"github.com/project/internal/service"
+	"github.com/project/pkg/github"
+	"github.com/project/pkg/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// emptyStore has no repository stored; any other method panics.
+type emptyStore struct {
+	repository.IGitRepo
+}
+
+func (emptyStore) GetRepo(context.Context, string, string, string) (*model.Repository, error) {
+	return nil, nil
+}
+
+// Test FetchRepo answers 429 straight away instead of waiting for a paced request
+func TestFetchRepoRateLimitedWithoutWaiting(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// a single request left for the next hour
+	reset := time.Now().Add(time.Hour).Unix()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "1")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+		w.Header().Set("X-RateLimit-Resource", object.ResourceCore)
+		_, _ = w.Write([]byte(`{"name": "api", "owner": {"login": "acme"}}`))
+	}))
+	defer srv.Close()
+
+	details := github.NewGithub(github.WithBaseURL(srv.URL), github.WithTokens("ghp_token"))
+	// learn the quota, then spend the one token the bucket holds
+	for i := 0; i < 2; i++ {
+		_, err := details.FetchRepo(context.Background(), "acme", "api")
+		require.NoError(t, err)
+	}
+
+	gitService := service.NewGitInfo(emptyStore{}, service.Provider{Name: "github", Host: "github.com", Details: details})
+	router := gin.New()
+	router.GET("/repos/:owner/:repo", NewHandler(gitService, nil, nil).FetchRepo)
+
+	started := time.Now()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/repos/acme/api", nil))
+
+	assert.Less(t, time.Since(started), time.Second)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.Greater(t, retryAfter, 60)
+}
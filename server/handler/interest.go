@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/project/internal/model"
+	"github.com/project/pkg/object"
+)
+
+type interestRequest struct {
+	Name string `json:"name"`
+	// Host is the provider host to search, the default one when left out.
+	Host            string             `json:"host"`
+	Query           object.SearchQuery `json:"query"`
+	IntervalMinutes int                `json:"interval_minutes"`
+	// Enabled defaults to true when left out.
+	Enabled *bool `json:"enabled"`
+}
+
+func (r interestRequest) toModel() model.Interest {
+	interest := model.Interest{
+		Name:            r.Name,
+		Host:            r.Host,
+		Query:           r.Query,
+		IntervalMinutes: r.IntervalMinutes,
+		Enabled:         true,
+	}
+	if r.Enabled != nil {
+		interest.Enabled = *r.Enabled
+	}
+
+	return interest
+}
+
+func (h *Handler) CreateInterest(c *gin.Context) {
+	var req interestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	interest, err := h.interests.CreateInterest(c.Request.Context(), req.toModel())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, interest)
+}
+
+func (h *Handler) GetInterests(c *gin.Context) {
+	interests, err := h.interests.GetInterests(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, interests)
+}
+
+func (h *Handler) GetInterest(c *gin.Context) {
+	id, ok := interestID(c)
+	if !ok {
+		return
+	}
+
+	interest, err := h.interests.GetInterest(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, interest)
+}
+
+func (h *Handler) UpdateInterest(c *gin.Context) {
+	id, ok := interestID(c)
+	if !ok {
+		return
+	}
+
+	var req interestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update := req.toModel()
+	update.ID = id
+	interest, err := h.interests.UpdateInterest(c.Request.Context(), update)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, interest)
+}
+
+func (h *Handler) DeleteInterest(c *gin.Context) {
+	id, ok := interestID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.interests.DeleteInterest(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) GetInterestRepos(c *gin.Context) {
+	id, ok := interestID(c)
+	if !ok {
+		return
+	}
+
+	repos, err := h.interests.GetInterestRepos(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, repos)
+}
+
+// interestID parses the :id path parameter, answering 400 when it is not a UUID.
+func interestID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid interest id"})
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
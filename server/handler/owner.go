@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/project/internal/model"
+)
+
+type trackedOwnerRequest struct {
+	// Host is the provider host of the owner, the default one when left out.
+	Host            string `json:"host"`
+	Login           string `json:"login"`
+	Kind            string `json:"kind"`
+	IncludeForks    bool   `json:"include_forks"`
+	IncludeArchived bool   `json:"include_archived"`
+	IntervalMinutes int    `json:"interval_minutes"`
+	// Enabled defaults to true when left out.
+	Enabled *bool `json:"enabled"`
+}
+
+func (r trackedOwnerRequest) toModel() model.TrackedOwner {
+	owner := model.TrackedOwner{
+		Host:            r.Host,
+		Login:           r.Login,
+		Kind:            r.Kind,
+		IncludeForks:    r.IncludeForks,
+		IncludeArchived: r.IncludeArchived,
+		IntervalMinutes: r.IntervalMinutes,
+		Enabled:         true,
+	}
+	if r.Enabled != nil {
+		owner.Enabled = *r.Enabled
+	}
+
+	return owner
+}
+
+func (h *Handler) CreateTrackedOwner(c *gin.Context) {
+	var req trackedOwnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	owner, err := h.owners.CreateTrackedOwner(c.Request.Context(), req.toModel())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, owner)
+}
+
+func (h *Handler) GetTrackedOwners(c *gin.Context) {
+	owners, err := h.owners.GetTrackedOwners(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, owners)
+}
+
+func (h *Handler) GetTrackedOwner(c *gin.Context) {
+	id, ok := ownerID(c)
+	if !ok {
+		return
+	}
+
+	owner, err := h.owners.GetTrackedOwner(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, owner)
+}
+
+func (h *Handler) UpdateTrackedOwner(c *gin.Context) {
+	id, ok := ownerID(c)
+	if !ok {
+		return
+	}
+
+	var req trackedOwnerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update := req.toModel()
+	update.ID = id
+	owner, err := h.owners.UpdateTrackedOwner(c.Request.Context(), update)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, owner)
+}
+
+func (h *Handler) DeleteTrackedOwner(c *gin.Context) {
+	id, ok := ownerID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.owners.DeleteTrackedOwner(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) GetOwnerRepos(c *gin.Context) {
+	id, ok := ownerID(c)
+	if !ok {
+		return
+	}
+
+	repos, err := h.owners.GetOwnerRepos(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, repos)
+}
+
+// ownerID parses the :id path parameter, answering 400 when it is not a UUID.
+func ownerID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid owner id"})
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/project/internal/service"
+)
+
+type trackRequest struct {
+	PollIntervalMinutes int `json:"poll_interval_minutes"`
+	FreshnessTTLMinutes int `json:"freshness_ttl_minutes"`
+	// Enabled defaults to true when left out.
+	Enabled *bool `json:"enabled"`
+}
+
+// TrackRepo puts a repository on the watchlist. Posting again updates its schedule.
+func (h *Handler) TrackRepo(c *gin.Context) {
+	var req trackRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings := service.WatchSettings{
+		PollIntervalMinutes: req.PollIntervalMinutes,
+		FreshnessTTLMinutes: req.FreshnessTTLMinutes,
+		Enabled:             true,
+	}
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+	}
+
+	ctx := service.WithoutWaiting(c.Request.Context())
+	repo, err := h.service.TrackRepo(ctx, c.Query("host"), c.Param("owner"), c.Param("repo"), settings)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, repo)
+}
+
+func (h *Handler) UntrackRepo(c *gin.Context) {
+	if err := h.service.UntrackRepo(c.Request.Context(), c.Query("host"), c.Param("owner"), c.Param("repo")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
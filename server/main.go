@@ -15,10 +15,13 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/project/config"
-	"github.com/project/internal/model"
 	"github.com/project/internal/repository"
 	"github.com/project/internal/service"
+	"github.com/project/pkg/bitbucket"
+	"github.com/project/pkg/gitea"
 	"github.com/project/pkg/github"
+	"github.com/project/pkg/gitlab"
+	"github.com/project/pkg/local"
 )
 
 func main() {
@@ -27,34 +30,90 @@ func main() {
 		log.Fatalf("loading env error: %v", err)
 	}
 
+	budgetShares := service.DefaultBudgetShares
+	if v := os.Getenv("RATE_BUDGET_SHARES"); v != "" {
+		budgetShares, err = service.ParseBudgetShares(v)
+		if err != nil {
+			log.Fatalf("Failed to parse RATE_BUDGET_SHARES: %v", err)
+		}
+	}
+
+	defaultProvider := service.Provider{Name: "github", Host: github.HostFromEnv(), BudgetShares: budgetShares}
+
 	db := config.GetDB()
-	if err := db.AutoMigrate(&model.Repository{}, &model.Commit{}); err != nil {
+	if err := repository.Migrate(db.DB, defaultProvider.Host); err != nil {
 		log.Fatalf("Failed to run production migrations: %v", err)
 	}
 	gitRepo := repository.NewGitDBRepo(db.DB)
-	gitService := service.NewGitInfo(gitRepo, github.NewGithub())
 
-	ticker := time.NewTicker(5 * time.Hour)
+	httpCache := repository.NewHTTPCache(db.DB)
+	githubOpts := []github.Option{github.WithCache(httpCache)}
+	appConfig, err := github.AppConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load GitHub App configuration: %v", err)
+	}
+	if appConfig != nil {
+		githubOpts = append(githubOpts, github.WithApp(*appConfig))
+	}
+	defaultProvider.Details = github.NewGithub(githubOpts...)
+
+	enterpriseHosts, err := github.EnterpriseHostsFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load GitHub Enterprise configuration: %v", err)
+	}
+
+	var providers []service.Provider
+	for _, host := range enterpriseHosts {
+		opts := append([]github.Option{github.WithCache(httpCache)}, host.Options()...)
+		providers = append(providers, service.Provider{Name: "github", Host: host.Host(), Details: github.NewGithub(opts...), BudgetShares: budgetShares})
+	}
+	if os.Getenv("GITLAB_BASE_URL") != "" {
+		providers = append(providers, service.Provider{Name: "gitlab", Host: gitlab.HostFromEnv(), Details: gitlab.NewGitlab()})
+	}
+	// Bitbucket Cloud needs no base URL, only credentials
+	if os.Getenv("BITBUCKET_BASE_URL") != "" || os.Getenv("BITBUCKET_USERNAME") != "" {
+		providers = append(providers, service.Provider{Name: "bitbucket", Host: bitbucket.HostFromEnv(), Details: bitbucket.NewBitbucket()})
+	}
+	if os.Getenv("GITEA_BASE_URL") != "" {
+		providers = append(providers, service.Provider{Name: "gitea", Host: gitea.HostFromEnv(), Details: gitea.NewGitea()})
+	}
+	if os.Getenv("GIT_LOCAL_ROOT") != "" {
+		providers = append(providers, service.Provider{Name: "local", Host: local.Host, Details: local.NewLocal()})
+	}
+	gitService := service.NewGitInfo(gitRepo, defaultProvider, providers...)
+
+	interestService := service.NewInterests(repository.NewInterestDBRepo(db.DB), gitService)
+	ownerService := service.NewOwners(repository.NewOwnerDBRepo(db.DB), gitService)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// tracked repositories carry their own poll intervals, the ticker only checks which are due
+	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
 
-	commitTicker := time.NewTicker(1 * time.Hour)
-	defer commitTicker.Stop()
+	// interests and tracked owners carry their own intervals, the ticker only checks which are due
+	interestTicker := time.NewTicker(1 * time.Minute)
+	defer interestTicker.Stop()
 
 	go func() {
 		for {
 			select {
 			case <-ticker.C:
 				log.Println("fetching repository data")
-				err = gitService.UpdateRepo(context.Background())
+				err = gitService.UpdateRepo(ctx)
 				if err != nil {
 					log.Printf("Error updating repository repository data: %v", err)
 				}
-			case <-commitTicker.C:
-				log.Println("search repositories of interest")
-				err = gitService.SearchRepos(context.Background(), "cryptocurrency")
-				if err != nil {
-					log.Printf("Error fetching repository: %v", err)
+			case <-interestTicker.C:
+				if err := interestService.RunDue(ctx); err != nil {
+					log.Printf("Error searching interests: %v", err)
+				}
+				if err := ownerService.RunDue(ctx); err != nil {
+					log.Printf("Error reconciling tracked owners: %v", err)
 				}
+			case <-ctx.Done():
+				return
 			}
 		}
 	}()
@@ -70,12 +129,31 @@ func main() {
 		port = p
 	}
 
-	handler := handlers.NewHandler(gitService)
+	handler := handlers.NewHandler(gitService, interestService, ownerService)
 
 	router := gin.Default()
+	// GitLab owners are namespace paths; match on the raw path so an encoded
+	// acme%2Fplatform stays a single :owner
+	router.UseRawPath = true
 	router.GET("/repos/language/:language", handler.FetchByLanguage)
 	router.GET("/repos/top/:n", handler.GetTopNRepoByStarCount)
+	router.GET("/repos/:owner/:repo", handler.FetchRepo)
+	router.POST("/repos/:owner/:repo/track", handler.TrackRepo)
+	router.DELETE("/repos/:owner/:repo/track", handler.UntrackRepo)
 	router.GET("/commit/:owner/:repo", handler.FetchCommit)
+	router.GET("/rate-limits", handler.GetRateLimits)
+	router.POST("/interests", handler.CreateInterest)
+	router.GET("/interests", handler.GetInterests)
+	router.GET("/interests/:id", handler.GetInterest)
+	router.PUT("/interests/:id", handler.UpdateInterest)
+	router.DELETE("/interests/:id", handler.DeleteInterest)
+	router.GET("/interests/:id/repos", handler.GetInterestRepos)
+	router.POST("/owners", handler.CreateTrackedOwner)
+	router.GET("/owners", handler.GetTrackedOwners)
+	router.GET("/owners/:id", handler.GetTrackedOwner)
+	router.PUT("/owners/:id", handler.UpdateTrackedOwner)
+	router.DELETE("/owners/:id", handler.DeleteTrackedOwner)
+	router.GET("/owners/:id/repos", handler.GetOwnerRepos)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
@@ -89,9 +167,6 @@ func main() {
 			log.Fatalf("listen: %s\n", err)
 		}
 	}()
-	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer stop()
-
 	<-ctx.Done()
 
 	log.Println("shutting down gracefully, press Ctrl+C again to force")